@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 
 	_ "github.com/lib/pq"
@@ -10,6 +9,7 @@ import (
 	"rsshub/internal/adapters/db"
 	"rsshub/internal/adapters/handlers"
 	"rsshub/internal/config"
+	"rsshub/pkg/logger"
 )
 
 func main() {
@@ -22,12 +22,24 @@ func main() {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		logger.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+	if err := logger.Init(cfg.LogFormat, cfg.LogLevel); err != nil {
+		logger.Error("Failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+
+	// The health check only talks to the background process, which checks
+	// the database itself.
+	if command == "health" {
+		handler.HandleHealth()
+		return
 	}
 
 	database, err := db.NewDB(cfg)
 	if err != nil {
-		fmt.Printf("Error connecting to database: %v\n", err)
+		logger.Error("Error connecting to database", "error", err)
 		os.Exit(1)
 	}
 	defer database.Close()
@@ -36,13 +48,31 @@ func main() {
 	case "fetch":
 		handler.HandleFetch(cfg, database)
 	case "add":
-		handler.HandleAdd(database)
+		handler.HandleAdd(cfg, database)
+	case "preview":
+		handler.HandlePreview(cfg)
 	case "list":
 		handler.HandleList(database)
+	case "feed":
+		handler.HandleFeed(database)
 	case "delete":
 		handler.HandleDelete(database)
 	case "articles":
 		handler.HandleArticles(database)
+	case "route":
+		handler.HandleRoute(cfg)
+	case "ingest":
+		handler.HandleIngest(cfg, database)
+	case "export":
+		handler.HandleExport(database)
+	case "webhook":
+		handler.HandleWebhook(database)
+	case "rule":
+		handler.HandleRule(database)
+	case "digest":
+		handler.HandleDigest(cfg, database)
+	case "filter":
+		handler.HandleFilter(cfg, database)
 	case "set-interval":
 		handler.HandleSetInterval(cfg)
 	case "set-workers":
@@ -62,10 +92,35 @@ func printHelp() {
 
   Common Commands:
      add             add new RSS feed
+     preview         fetch a feed and check it for problems without adding it
      set-interval    set RSS fetch interval
      set-workers     set number of workers
+     health          check that the background process is working
      list            list available RSS feeds
+     feed show       show details of an RSS feed
+     feed full-text  turn full-text extraction on or off for a feed
+     feed tags       set the tags of a feed
      delete          delete RSS feed
      articles        show latest articles
+     route list      list scraper routes
+     route test      run a scraper route and show the items it finds
+     ingest          store a feed read from stdin in an existing feed
+     export          write a feed's articles as RSS 2.0 to stdout
+     webhook add     post new articles of a feed or tag to a URL
+     webhook list    list webhooks
+     webhook delete  delete a webhook
+     webhook log     show recent webhook deliveries
+     rule add        alert on new articles matching a query
+     rule list       list alert rules and their matches
+     rule test       run a rule or query against past articles
+     rule delete     delete an alert rule
+     digest add      email a daily or weekly digest of new articles
+     digest list     list digests and when they are sent next
+     digest send     send digests now
+     digest delete   delete a digest
+     filter add      drop or rewrite items of a feed before they are stored
+     filter list     list the filters of a feed
+     filter test     fetch a feed and show what its filters do
+     filter delete   delete a filter
      fetch           starts the background process that periodically fetches and processes RSS feeds using a worker pool`)
 }
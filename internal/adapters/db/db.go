@@ -1,10 +1,12 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"rsshub/internal/config"
 	models "rsshub/internal/domain"
@@ -41,12 +43,52 @@ func (d *DB) AddFeed(feed *models.Feed) error {
 		}
 		feed.ID = id
 	}
-	_, err := d.Exec(`INSERT INTO feeds (id, name, url) VALUES ($1, $2, $3)`, feed.ID, feed.Name, feed.URL)
+	tags := append([]string{}, feed.Tags...)
+	_, err := d.Exec(`INSERT INTO feeds (id, name, url, full_text, tags) VALUES ($1, $2, $3, $4, $5)`,
+		feed.ID, feed.Name, feed.URL, feed.FullText, pq.Array(tags))
 	return err
 }
 
+// SetFeedTags replaces the tags of the named feed. It returns
+// sql.ErrNoRows if there is no such feed.
+func (d *DB) SetFeedTags(name string, tags []string) error {
+	res, err := d.Exec(`UPDATE feeds SET tags = $2 WHERE name = $1`, name, pq.Array(append([]string{}, tags...)))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// feedColumns is the column list read by scanFeed.
+const feedColumns = `id, created_at, updated_at, name, url, title, site_url, description, language, icon_url, last_build_date, generator, ttl, full_text, tags, hub_url, self_url, parse_status, parse_messages`
+
+func scanFeed(row scanner) (models.Feed, error) {
+	var f models.Feed
+	var updated, lastBuild sql.NullTime
+	err := row.Scan(&f.ID, &f.CreatedAt, &updated, &f.Name, &f.URL, &f.Title, &f.SiteURL, &f.Description, &f.Language, &f.IconURL,
+		&lastBuild, &f.Generator, &f.TTL, &f.FullText, pq.Array(&f.Tags), &f.HubURL, &f.SelfURL, &f.ParseStatus, pq.Array(&f.ParseMessages))
+	if err != nil {
+		return f, err
+	}
+	if updated.Valid {
+		f.UpdatedAt = updated.Time
+	}
+	if lastBuild.Valid {
+		f.LastBuildDate = lastBuild.Time
+	}
+	return f, nil
+}
+
 func (d *DB) ListFeeds(limit int) ([]models.Feed, error) {
-	query := `SELECT id, created_at, updated_at, name, url FROM feeds ORDER BY created_at DESC`
+	query := `SELECT ` + feedColumns + ` FROM feeds ORDER BY created_at DESC`
 	if limit > 0 {
 		query += fmt.Sprintf(" LIMIT %d", limit)
 	}
@@ -59,34 +101,69 @@ func (d *DB) ListFeeds(limit int) ([]models.Feed, error) {
 
 	var feeds []models.Feed
 	for rows.Next() {
-		var f models.Feed
-		var updated sql.NullTime
-		err := rows.Scan(&f.ID, &f.CreatedAt, &updated, &f.Name, &f.URL)
+		f, err := scanFeed(rows)
 		if err != nil {
 			return nil, err
 		}
-		if updated.Valid {
-			f.UpdatedAt = updated.Time
-		}
 		feeds = append(feeds, f)
 	}
 	return feeds, nil
 }
 
+// GetFeed returns the named feed, or sql.ErrNoRows if there is none.
+func (d *DB) GetFeed(name string) (*models.Feed, error) {
+	f, err := scanFeed(d.QueryRow(`SELECT `+feedColumns+` FROM feeds WHERE name = $1`, name))
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
 func (d *DB) DeleteFeed(name string) error {
 	_, err := d.Exec(`DELETE FROM feeds WHERE name = $1`, name)
 	return err
 }
 
-func (d *DB) GetArticles(feedName string, limit int) ([]models.Article, error) {
-	query := `SELECT a.id, a.created_at, a.updated_at, a.title, a.link, a.published_at, a.description, a.feed_id
+// articleColumns is the column list, on articles aliased as a, read by
+// scanArticle.
+const articleColumns = `a.id, a.created_at, a.updated_at, a.guid, a.title, a.link, a.published_at, a.description, a.content,
+        a.plain_text, a.full_content, a.extracted_at, a.authors, a.categories, a.content_hash, a.cluster_id, a.feed_id`
+
+// articleFilterSQL is the WHERE condition for an ArticleFilter given as
+// $1 feed name, $2 author and $3 category, on articles a joined to feeds f.
+const articleFilterSQL = `($1 = '' OR f.name = $1)
+        AND ($2 = '' OR EXISTS (SELECT 1 FROM unnest(a.authors) au WHERE au ILIKE '%' || $2 || '%'))
+        AND ($3 = '' OR EXISTS (SELECT 1 FROM unnest(a.categories) c WHERE lower(c) = lower($3)))`
+
+// scanArticle scans articleColumns followed by any extra columns.
+func scanArticle(row scanner, a *models.Article, extra ...any) error {
+	var updated, extracted sql.NullTime
+	var description sql.NullString
+	dest := []any{&a.ID, &a.CreatedAt, &updated, &a.GUID, &a.Title, &a.Link, &a.PublishedAt, &description, &a.Content,
+		&a.PlainText, &a.FullContent, &extracted, pq.Array(&a.Authors), pq.Array(&a.Categories), &a.ContentHash, &a.ClusterID, &a.FeedID}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	if updated.Valid {
+		a.UpdatedAt = updated.Time
+	}
+	if extracted.Valid {
+		a.ExtractedAt = extracted.Time
+	}
+	a.Description = description.String
+	return nil
+}
+
+// GetArticles returns the latest articles matching the filter.
+func (d *DB) GetArticles(filter models.ArticleFilter) ([]models.Article, error) {
+	query := `SELECT ` + articleColumns + `
       FROM articles a
       JOIN feeds f ON a.feed_id = f.id
-      WHERE f.name = $1
+      WHERE ` + articleFilterSQL + `
       ORDER BY a.published_at DESC
-      LIMIT $2`
+      LIMIT $4`
 
-	rows, err := d.Query(query, feedName, limit)
+	rows, err := d.Query(query, filter.FeedName, filter.Author, filter.Category, filter.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -95,23 +172,67 @@ func (d *DB) GetArticles(feedName string, limit int) ([]models.Article, error) {
 	var articles []models.Article
 	for rows.Next() {
 		var a models.Article
-		var updated sql.NullTime
-		err := rows.Scan(&a.ID, &a.CreatedAt, &updated, &a.Title, &a.Link, &a.PublishedAt, &a.Description, &a.FeedID)
-		if err != nil {
+		if err := scanArticle(rows, &a); err != nil {
 			return nil, err
 		}
-		if updated.Valid {
-			a.UpdatedAt = updated.Time
-		}
 		articles = append(articles, a)
 	}
 	return articles, nil
 }
 
-func (d *DB) GetOutdatedFeeds(limit int) ([]models.Feed, error) {
-	query := `SELECT id, created_at, updated_at, name, url FROM feeds ORDER BY updated_at ASC NULLS FIRST LIMIT $1`
+// GetStories returns the latest stories that have at least one article
+// matching the filter. Each story lists all the feeds it appeared in.
+func (d *DB) GetStories(filter models.ArticleFilter) ([]models.Story, error) {
+	query := `WITH clusters AS (
+        SELECT DISTINCT a.cluster_id FROM articles a
+        JOIN feeds f ON a.feed_id = f.id
+        WHERE ` + articleFilterSQL + `
+      ), stories AS (
+        SELECT a.cluster_id, array_agg(DISTINCT f.name) AS sources, COUNT(*) AS articles, MAX(a.published_at) AS latest
+        FROM articles a
+        JOIN feeds f ON a.feed_id = f.id
+        JOIN clusters c ON a.cluster_id = c.cluster_id
+        GROUP BY a.cluster_id
+      ), leads AS (
+        SELECT DISTINCT ON (a.cluster_id) a.* FROM articles a
+        JOIN clusters c ON a.cluster_id = c.cluster_id
+        ORDER BY a.cluster_id, a.published_at, a.created_at
+      )
+      SELECT ` + articleColumns + `, s.sources, s.articles
+      FROM leads a
+      JOIN stories s ON s.cluster_id = a.cluster_id
+      ORDER BY s.latest DESC
+      LIMIT $4`
 
-	rows, err := d.Query(query, limit)
+	rows, err := d.Query(query, filter.FeedName, filter.Author, filter.Category, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stories []models.Story
+	for rows.Next() {
+		var s models.Story
+		if err := scanArticle(rows, &s.Article, pq.Array(&s.Sources), &s.Articles); err != nil {
+			return nil, err
+		}
+		stories = append(stories, s)
+	}
+	return stories, nil
+}
+
+// GetOutdatedFeeds returns the feeds fetched longest ago. Feeds with an
+// active WebSub subscription get their updates pushed and are skipped until
+// they were last fetched more than pushedInterval ago, so that they are
+// still polled now and then in case pushes go missing.
+func (d *DB) GetOutdatedFeeds(ctx context.Context, limit int, pushedInterval time.Duration) ([]models.Feed, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds f
+      WHERE NOT EXISTS (SELECT 1 FROM websub_subscriptions s
+        WHERE s.feed_id = f.id AND s.state = 'active' AND s.expires_at > CURRENT_TIMESTAMP
+          AND f.updated_at > CURRENT_TIMESTAMP - make_interval(secs => $2))
+      ORDER BY updated_at ASC NULLS FIRST LIMIT $1`
+
+	rows, err := d.QueryContext(ctx, query, limit, pushedInterval.Seconds())
 	if err != nil {
 		return nil, err
 	}
@@ -119,40 +240,64 @@ func (d *DB) GetOutdatedFeeds(limit int) ([]models.Feed, error) {
 
 	var feeds []models.Feed
 	for rows.Next() {
-		var f models.Feed
-		var updated sql.NullTime
-		err := rows.Scan(&f.ID, &f.CreatedAt, &updated, &f.Name, &f.URL)
+		f, err := scanFeed(rows)
 		if err != nil {
 			return nil, err
 		}
-		if updated.Valid {
-			f.UpdatedAt = updated.Time
-		}
 		feeds = append(feeds, f)
 	}
 	return feeds, nil
 }
 
-func (d *DB) ArticleExists(feedID string, link string) (bool, error) {
-	var count int
-	err := d.QueryRow(`SELECT COUNT(*) FROM articles WHERE feed_id = $1 AND link = $2`, feedID, link).Scan(&count)
-	return count > 0, err
-}
+func (d *DB) GetArticleRevisions(articleID string) ([]models.ArticleRevision, error) {
+	rows, err := d.Query(`SELECT id, created_at, article_id, title, published_at, description, content, content_hash
+      FROM article_revisions WHERE article_id = $1 ORDER BY created_at DESC`, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-func (d *DB) InsertArticle(article *models.Article) error {
-	if article.ID == "" {
-		id, err := uuid.New()
+	var revisions []models.ArticleRevision
+	for rows.Next() {
+		var r models.ArticleRevision
+		var description sql.NullString
+		err := rows.Scan(&r.ID, &r.CreatedAt, &r.ArticleID, &r.Title, &r.PublishedAt, &description, &r.Content, &r.ContentHash)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		article.ID = id
+		r.Description = description.String
+		revisions = append(revisions, r)
 	}
-	_, err := d.Exec(`INSERT INTO articles (id, title, link, published_at, description, feed_id)
-      VALUES ($1, $2, $3, $4, $5, $6)`, article.ID, article.Title, article.Link, article.PublishedAt, article.Description, article.FeedID)
-	return err
+	return revisions, nil
 }
 
-func (d *DB) UpdateFeedUpdatedAt(id string) error {
-	_, err := d.Exec(`UPDATE feeds SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
-	return err
+// LoadMedia fills in the media of the given articles.
+func (d *DB) LoadMedia(articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	ids := make([]string, len(articles))
+	index := make(map[string]int, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+		index[a.ID] = i
+	}
+
+	rows, err := d.Query(`SELECT id, article_id, url, mime_type, length, duration, thumbnail_url
+      FROM article_media WHERE article_id = ANY($1::uuid[]) ORDER BY article_id, position`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.Media
+		err := rows.Scan(&m.ID, &m.ArticleID, &m.URL, &m.MIMEType, &m.Length, &m.Duration, &m.ThumbnailURL)
+		if err != nil {
+			return err
+		}
+		a := &articles[index[m.ArticleID]]
+		a.Media = append(a.Media, m)
+	}
+	return rows.Err()
 }
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/uuid"
+)
+
+// digestColumns is the column list, on digests aliased as d left joined to
+// feeds f, read by scanDigest.
+const digestColumns = `d.id, d.created_at, d.name, d.recipients, d.schedule, d.send_time, d.weekday, d.group_by,
+        COALESCE(d.feed_id::text, ''), COALESCE(f.name, ''), d.tag, d.last_sent_at, d.last_article_at,
+        COALESCE(d.last_article_id::text, '')`
+
+func scanDigest(row scanner) (models.Digest, error) {
+	var dg models.Digest
+	var weekday int
+	var sent, lastArticle sql.NullTime
+	if err := row.Scan(&dg.ID, &dg.CreatedAt, &dg.Name, pq.Array(&dg.Recipients), &dg.Schedule, &dg.SendTime, &weekday,
+		&dg.GroupBy, &dg.FeedID, &dg.FeedName, &dg.Tag, &sent, &lastArticle, &dg.LastArticleID); err != nil {
+		return dg, err
+	}
+	dg.Weekday = time.Weekday(weekday)
+	if sent.Valid {
+		dg.LastSentAt = sent.Time
+	}
+	if lastArticle.Valid {
+		dg.LastArticleAt = lastArticle.Time
+	}
+	return dg, nil
+}
+
+// AddDigest stores a digest.
+func (d *DB) AddDigest(dg *models.Digest) error {
+	id, err := uuid.New()
+	if err != nil {
+		return err
+	}
+	dg.ID = id
+	feedID := sql.NullString{String: dg.FeedID, Valid: dg.FeedID != ""}
+	_, err = d.Exec(`INSERT INTO digests (id, name, recipients, schedule, send_time, weekday, group_by, feed_id, tag)
+      VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		dg.ID, dg.Name, pq.Array(dg.Recipients), dg.Schedule, dg.SendTime, int(dg.Weekday), dg.GroupBy, feedID, dg.Tag)
+	return err
+}
+
+// GetDigest returns the named digest, or sql.ErrNoRows if there is none.
+func (d *DB) GetDigest(name string) (*models.Digest, error) {
+	dg, err := scanDigest(d.QueryRow(`SELECT `+digestColumns+` FROM digests d LEFT JOIN feeds f ON f.id = d.feed_id
+      WHERE d.name = $1`, name))
+	if err != nil {
+		return nil, err
+	}
+	return &dg, nil
+}
+
+// ListDigests returns all digests, by name.
+func (d *DB) ListDigests(ctx context.Context) ([]models.Digest, error) {
+	rows, err := d.QueryContext(ctx, `SELECT `+digestColumns+` FROM digests d LEFT JOIN feeds f ON f.id = d.feed_id
+      ORDER BY d.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []models.Digest
+	for rows.Next() {
+		dg, err := scanDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, dg)
+	}
+	return digests, rows.Err()
+}
+
+// DeleteDigest deletes the named digest. It returns sql.ErrNoRows if there
+// is no such digest.
+func (d *DB) DeleteDigest(name string) error {
+	res, err := d.Exec(`DELETE FROM digests WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDigestArticles returns, oldest first, the articles in the scope of a
+// digest that were added after the last one it sent, or within period if it
+// has not sent any yet. Articles are ordered by creation time and id, so
+// that a digest cut at limit resumes after its last article even when the
+// next ones were added at the same time.
+func (d *DB) GetDigestArticles(ctx context.Context, dg models.Digest, period time.Duration, limit int) ([]models.DigestArticle, error) {
+	since := sql.NullTime{Time: dg.LastArticleAt, Valid: !dg.LastArticleAt.IsZero()}
+	// Without a last id, all the articles added at the last time are past
+	// the cursor, as they were before ids were recorded.
+	after := sql.NullString{String: dg.LastArticleID, Valid: dg.LastArticleID != ""}
+	rows, err := d.QueryContext(ctx, `SELECT `+articleColumns+`, f.name, f.title, f.tags
+      FROM articles a
+      JOIN feeds f ON a.feed_id = f.id
+      WHERE (a.created_at, a.id) > (COALESCE($1, CURRENT_TIMESTAMP - make_interval(secs => $2)),
+                                    COALESCE($6::uuid, 'ffffffff-ffff-ffff-ffff-ffffffffffff'))
+        AND ($3 = '' OR f.id::text = $3) AND ($4 = '' OR $4 = ANY(f.tags))
+      ORDER BY a.created_at, a.id
+      LIMIT $5`, since, period.Seconds(), dg.FeedID, dg.Tag, limit, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []models.DigestArticle
+	for rows.Next() {
+		var a models.DigestArticle
+		if err := scanArticle(rows, &a.Article, &a.FeedName, &a.FeedTitle, pq.Array(&a.FeedTags)); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// MarkDigestSent records that a digest was sent at sentAt with the articles
+// up to last, the cursor the next digest starts after, which is kept if
+// last is nil.
+func (d *DB) MarkDigestSent(ctx context.Context, id string, sentAt time.Time, last *models.Article) error {
+	var lastAt sql.NullTime
+	var lastID sql.NullString
+	if last != nil {
+		lastAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		lastID = sql.NullString{String: last.ID, Valid: true}
+	}
+	_, err := d.ExecContext(ctx, `UPDATE digests SET last_sent_at = $2,
+        last_article_at = COALESCE($3, last_article_at), last_article_id = COALESCE($4::uuid, last_article_id)
+      WHERE id = $1`, id, sentAt.UTC(), lastAt, lastID)
+	return err
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	models "rsshub/internal/domain"
+)
+
+// SetFeedFullText turns full-text extraction on or off for the named feed.
+// It returns sql.ErrNoRows if there is no such feed.
+func (d *DB) SetFeedFullText(name string, on bool) error {
+	res, err := d.Exec(`UPDATE feeds SET full_text = $2 WHERE name = $1`, name, on)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPendingExtractions returns the oldest articles of full-text feeds whose
+// page has not been extracted yet and that failed fewer than maxAttempts
+// times, skipping the articles in exclude.
+func (d *DB) GetPendingExtractions(ctx context.Context, limit, maxAttempts int, exclude []string) ([]models.Article, error) {
+	rows, err := d.QueryContext(ctx, `SELECT `+articleColumns+`
+      FROM articles a
+      JOIN feeds f ON a.feed_id = f.id
+      WHERE f.full_text
+        AND a.extracted_at IS NULL
+        AND a.extract_attempts < $2
+        AND a.link <> ''
+        AND a.id <> ALL($3::uuid[])
+      ORDER BY a.extract_attempts, a.created_at
+      LIMIT $1`, limit, maxAttempts, pq.Array(exclude))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	for rows.Next() {
+		var a models.Article
+		if err := scanArticle(rows, &a); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// SaveExtraction stores the content extracted from the article's page and
+// the plain text derived from it, which replaces the text of the summary.
+func (d *DB) SaveExtraction(ctx context.Context, articleID, content, plainText string) error {
+	_, err := d.ExecContext(ctx, `UPDATE articles SET full_content = $2, plain_text = $3,
+        extracted_at = CURRENT_TIMESTAMP, extract_error = ''
+      WHERE id = $1`, articleID, content, plainText)
+	return err
+}
+
+// FailExtraction records a failed attempt to extract the article's page.
+func (d *DB) FailExtraction(ctx context.Context, articleID string, reason string) error {
+	_, err := d.ExecContext(ctx, `UPDATE articles SET extract_attempts = extract_attempts + 1, extract_error = $2
+      WHERE id = $1`, articleID, reason)
+	return err
+}
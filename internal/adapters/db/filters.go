@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/uuid"
+)
+
+// filterColumns is the column list read by scanFilter.
+const filterColumns = `id, created_at, feed_id, position, action, field, pattern, replacement, min_length`
+
+func scanFilter(row scanner) (models.Filter, error) {
+	var f models.Filter
+	err := row.Scan(&f.ID, &f.CreatedAt, &f.FeedID, &f.Position, &f.Action, &f.Field, &f.Pattern, &f.Replacement, &f.MinLength)
+	return f, err
+}
+
+// AddFilter appends a filter to the ingest pipeline of its feed.
+func (d *DB) AddFilter(filter *models.Filter) error {
+	id, err := uuid.New()
+	if err != nil {
+		return err
+	}
+	filter.ID = id
+	return d.QueryRow(`INSERT INTO filters (id, feed_id, position, action, field, pattern, replacement, min_length)
+      VALUES ($1, $2, (SELECT COALESCE(MAX(position), 0) + 1 FROM filters WHERE feed_id = $2), $3, $4, $5, $6, $7)
+      RETURNING position`,
+		filter.ID, filter.FeedID, filter.Action, filter.Field, filter.Pattern, filter.Replacement, filter.MinLength).
+		Scan(&filter.Position)
+}
+
+// GetFeedFilters returns the ingest pipeline of a feed, in order.
+func (d *DB) GetFeedFilters(ctx context.Context, feedID string) ([]models.Filter, error) {
+	rows, err := d.QueryContext(ctx, `SELECT `+filterColumns+` FROM filters WHERE feed_id = $1 ORDER BY position`, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filters []models.Filter
+	for rows.Next() {
+		f, err := scanFilter(rows)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, rows.Err()
+}
+
+// DeleteFilter removes a filter from its pipeline. It returns
+// sql.ErrNoRows if there is no such filter.
+func (d *DB) DeleteFilter(id string) error {
+	res, err := d.Exec(`DELETE FROM filters WHERE id::text = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/simhash"
+	"rsshub/pkg/tracing"
+	"rsshub/pkg/uuid"
+)
+
+// insertBatchSize keeps bulk inserts well below the PostgreSQL limit of
+// 65535 bind parameters per statement.
+const insertBatchSize = 500
+
+// IngestArticles stores the articles of one fetch of a feed in a single
+// transaction: new articles are bulk inserted, each as a story of its own
+// until ClusterArticles is run, articles whose content changed are updated
+// with their old version kept as a revision, and the feed's metadata is
+// refreshed and it is marked as updated. Articles must have their GUID,
+// FeedID, ContentHash and SimHash set. Each step is traced as a child span
+// of ctx.
+func (d *DB) IngestArticles(ctx context.Context, feed models.Feed, articles []models.Article) (result *models.IngestResult, err error) {
+	defer observe("transaction", time.Now())
+	ctx, span := tracing.Start(ctx, "db.ingest", "articles", len(articles))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result = &models.IngestResult{}
+	fresh, err := dedupArticles(ctx, tx, feed.ID, articles, result)
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(fresh); start += insertBatchSize {
+		end := min(start+insertBatchSize, len(fresh))
+		batchCtx, batch := tracing.Start(ctx, "ingest.insert_batch", "batch.size", end-start)
+		inserted, err := insertArticles(batchCtx, tx, fresh[start:end])
+		batch.Set("inserted", len(inserted))
+		batch.RecordError(err)
+		batch.End()
+		if err != nil {
+			return nil, fmt.Errorf("error inserting articles: %v", err)
+		}
+		result.Inserted = append(result.Inserted, inserted...)
+	}
+
+	if err := traced(ctx, "ingest.media", func(ctx context.Context) error {
+		return insertMedia(ctx, tx, append(result.Inserted, result.Updated...))
+	}); err != nil {
+		return nil, fmt.Errorf("error inserting article media: %v", err)
+	}
+
+	if err := traced(ctx, "ingest.update_feed", func(ctx context.Context) error {
+		return updateFeed(ctx, tx, feed)
+	}); err != nil {
+		return nil, fmt.Errorf("error updating feed: %v", err)
+	}
+
+	if err := traced(ctx, "ingest.commit", func(context.Context) error {
+		return tx.Commit()
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// dedupArticles sorts the articles into those not stored yet, which it
+// returns, and those whose content changed, which it updates and adds to
+// result. Repeated GUIDs count once.
+func dedupArticles(ctx context.Context, tx *sql.Tx, feedID string, articles []models.Article, result *models.IngestResult) (fresh []models.Article, err error) {
+	ctx, span := tracing.Start(ctx, "ingest.dedup", "articles", len(articles))
+	defer func() {
+		span.Set("new", len(fresh), "updated", len(result.Updated))
+		span.RecordError(err)
+		span.End()
+	}()
+
+	existing, err := existingArticles(ctx, tx, feedID, articles)
+	if err != nil {
+		return nil, fmt.Errorf("error checking article existence: %v", err)
+	}
+	span.Set("existing", len(existing))
+
+	seen := make(map[string]bool)
+	for _, article := range articles {
+		if seen[article.GUID] {
+			continue
+		}
+		seen[article.GUID] = true
+
+		stored, ok := existing[article.GUID]
+		if !ok {
+			fresh = append(fresh, article)
+			continue
+		}
+		if stored.GUID != article.GUID {
+			if _, err := execTx(ctx, tx, `UPDATE articles SET guid = $1 WHERE id = $2`, article.GUID, stored.ID); err != nil {
+				return nil, fmt.Errorf("error updating article GUID: %v", err)
+			}
+		}
+		if stored.ContentHash == "" {
+			// Stored before content hashes were kept: there is nothing to
+			// compare with, so the article is taken as unchanged.
+			if _, err := execTx(ctx, tx, `UPDATE articles SET content_hash = $1 WHERE id = $2`, article.ContentHash, stored.ID); err != nil {
+				return nil, fmt.Errorf("error updating article content hash: %v", err)
+			}
+			continue
+		}
+		if stored.ContentHash == article.ContentHash && stored.Link == article.Link {
+			continue
+		}
+		article.ID = stored.ID
+		if err := updateArticle(ctx, tx, &article); err != nil {
+			return nil, fmt.Errorf("error updating article: %v", err)
+		}
+		if _, err := execTx(ctx, tx, `DELETE FROM article_media WHERE article_id = $1`, article.ID); err != nil {
+			return nil, fmt.Errorf("error updating article media: %v", err)
+		}
+		result.Updated = append(result.Updated, article)
+	}
+	return fresh, nil
+}
+
+// traced runs fn in a span named name.
+func traced(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, name)
+	defer span.End()
+	err := fn(ctx)
+	span.RecordError(err)
+	return err
+}
+
+// existingArticles returns the stored articles of the feed by the GUID of
+// the article they are a version of. Articles whose GUID is not stored are
+// matched by link with rows whose GUID is their link, which are those
+// stored before GUIDs were kept and those of items that had no GUID; the
+// caller moves the GUID onto such rows.
+func existingArticles(ctx context.Context, tx *sql.Tx, feedID string, articles []models.Article) (map[string]models.Article, error) {
+	guids := make([]string, len(articles))
+	for i, a := range articles {
+		guids[i] = a.GUID
+	}
+	existing, err := scanArticles(ctx, tx, byGUID,
+		`SELECT id, guid, link, content_hash FROM articles WHERE feed_id = $1 AND guid = ANY($2)`, feedID, pq.Array(guids))
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, a := range articles {
+		if _, ok := existing[a.GUID]; !ok && a.Link != "" {
+			links = append(links, a.Link)
+		}
+	}
+	if len(links) == 0 {
+		return existing, nil
+	}
+	legacy, err := scanArticles(ctx, tx, byLink, `SELECT id, guid, link, content_hash FROM articles
+      WHERE feed_id = $1 AND guid = link AND link = ANY($2)`, feedID, pq.Array(links))
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range articles {
+		if _, ok := existing[a.GUID]; ok {
+			continue
+		}
+		// Each row is taken over by one article only.
+		if stored, ok := legacy[a.Link]; ok {
+			existing[a.GUID] = stored
+			delete(legacy, a.Link)
+		}
+	}
+	return existing, nil
+}
+
+func byGUID(a models.Article) string { return a.GUID }
+func byLink(a models.Article) string { return a.Link }
+
+// scanArticles returns the ID, GUID, link and content hash of the articles
+// a query returns, by the key that key picks.
+func scanArticles(ctx context.Context, tx *sql.Tx, key func(models.Article) string, query string, args ...any) (map[string]models.Article, error) {
+	rows, err := queryTx(ctx, tx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	articles := make(map[string]models.Article)
+	for rows.Next() {
+		var a models.Article
+		if err := rows.Scan(&a.ID, &a.GUID, &a.Link, &a.ContentHash); err != nil {
+			return nil, err
+		}
+		articles[key(a)] = a
+	}
+	return articles, rows.Err()
+}
+
+// insertArticles inserts the articles with one statement and returns the
+// ones that were actually inserted; rows that lost a race with a concurrent
+// insert of the same GUID are skipped.
+func insertArticles(ctx context.Context, tx *sql.Tx, articles []models.Article) ([]models.Article, error) {
+	const columns = 14
+	byID := make(map[string]models.Article, len(articles))
+	placeholders := make([]string, 0, len(articles))
+	args := make([]any, 0, len(articles)*columns)
+	for i := range articles {
+		a := articles[i]
+		if a.ID == "" {
+			id, err := uuid.New()
+			if err != nil {
+				return nil, err
+			}
+			a.ID = id
+		}
+		a.ClusterID = a.ID
+		byID[a.ID] = a
+
+		n := len(args)
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13, n+14))
+		args = append(args, a.ID, a.GUID, a.Title, a.Link, a.PublishedAt, a.Description, a.Content, a.PlainText,
+			pq.Array(a.Authors), pq.Array(a.Categories), a.ContentHash, nullSimHash(a.SimHash), a.ClusterID, a.FeedID)
+	}
+
+	rows, err := queryTx(ctx, tx, `INSERT INTO articles (id, guid, title, link, published_at, description, content,
+        plain_text, authors, categories, content_hash, simhash, cluster_id, feed_id)
+      VALUES `+strings.Join(placeholders, ", ")+`
+      ON CONFLICT (feed_id, guid) DO NOTHING
+      RETURNING id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var inserted []models.Article
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		inserted = append(inserted, byID[id])
+	}
+	return inserted, rows.Err()
+}
+
+// clusterLock is the key of the advisory lock that serializes clustering.
+const clusterLock = 0x72737368_636c7573 // "rsshclus"
+
+// ClusterArticles moves each of the stored articles into the story of the
+// earliest article published within three days of it whose SimHash differs
+// in at most maxDistance bits, and sets their ClusterID. Articles of the
+// same batch are not matched against each other so that the result does
+// not depend on their order. Candidates are found through the indexed
+// SimHash bands, which only finds all of them if maxDistance is below
+// simhash.Bands. It is run after the ingest transaction has committed, so
+// that the search does not hold its locks, for inserted articles and again
+// for updated ones, whose text may have changed. Runs are serialized so
+// that near-duplicates clustered at the same time by different workers see
+// each other's story.
+func (d *DB) ClusterArticles(ctx context.Context, articles []models.Article, maxDistance int) (err error) {
+	if len(articles) == 0 {
+		return nil
+	}
+	if maxDistance >= simhash.Bands {
+		return fmt.Errorf("distance %d is not below the %d SimHash bands", maxDistance, simhash.Bands)
+	}
+	ctx, span := tracing.Start(ctx, "db.cluster", "articles", len(articles))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	ids := make([]string, len(articles))
+	index := make(map[string]int, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+		index[a.ID] = i
+	}
+
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := execTx(ctx, tx, `SELECT pg_advisory_xact_lock($1)`, int64(clusterLock)); err != nil {
+		return err
+	}
+
+	rows, err := queryTx(ctx, tx, `UPDATE articles n SET cluster_id = COALESCE((
+        SELECT o.cluster_id FROM articles o
+        WHERE o.id <> ALL($1::uuid[])
+          AND (o.simhash_band0 = n.simhash_band0 OR o.simhash_band1 = n.simhash_band1
+            OR o.simhash_band2 = n.simhash_band2 OR o.simhash_band3 = n.simhash_band3)
+          AND o.published_at BETWEEN n.published_at - INTERVAL '3 days' AND n.published_at + INTERVAL '3 days'
+          AND bit_count((o.simhash # n.simhash)::bit(64)) <= $2
+        ORDER BY o.published_at, o.created_at, o.id
+        LIMIT 1), n.id)
+      WHERE n.id = ANY($1::uuid[])
+      RETURNING n.id, n.cluster_id`, pq.Array(ids), maxDistance)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	clusters := make(map[string]string, len(articles))
+	for rows.Next() {
+		var id, clusterID string
+		if err := rows.Scan(&id, &clusterID); err != nil {
+			rows.Close()
+			return err
+		}
+		clusters[id] = clusterID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for id, clusterID := range clusters {
+		articles[index[id]].ClusterID = clusterID
+	}
+	return nil
+}
+
+// insertMedia stores the media of the articles, which must already exist.
+func insertMedia(ctx context.Context, tx *sql.Tx, articles []models.Article) error {
+	type row struct {
+		media    models.Media
+		position int
+	}
+	var media []row
+	for i := range articles {
+		for j := range articles[i].Media {
+			m := &articles[i].Media[j]
+			id, err := uuid.New()
+			if err != nil {
+				return err
+			}
+			m.ID = id
+			m.ArticleID = articles[i].ID
+			media = append(media, row{*m, j})
+		}
+	}
+
+	for start := 0; start < len(media); start += insertBatchSize {
+		batch := media[start:min(start+insertBatchSize, len(media))]
+		placeholders := make([]string, 0, len(batch))
+		args := make([]any, 0, len(batch)*8)
+		for _, r := range batch {
+			m := r.media
+			n := len(args)
+			placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8))
+			args = append(args, m.ID, m.ArticleID, r.position, m.URL, m.MIMEType, m.Length, m.Duration, m.ThumbnailURL)
+		}
+		_, err := execTx(ctx, tx, `INSERT INTO article_media (id, article_id, position, url, mime_type, length, duration, thumbnail_url)
+      VALUES `+strings.Join(placeholders, ", "), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateFeed(ctx context.Context, tx *sql.Tx, feed models.Feed) error {
+	lastBuild := sql.NullTime{Time: feed.LastBuildDate, Valid: !feed.LastBuildDate.IsZero()}
+	_, err := execTx(ctx, tx, `UPDATE feeds SET updated_at = CURRENT_TIMESTAMP,
+        title = $2, site_url = $3, description = $4, language = $5, icon_url = $6, last_build_date = $7, generator = $8, ttl = $9,
+        hub_url = $10, self_url = $11, parse_status = $12, parse_messages = $13
+      WHERE id = $1`, feed.ID, feed.Title, feed.SiteURL, feed.Description, feed.Language, feed.IconURL, lastBuild, feed.Generator, feed.TTL,
+		feed.HubURL, feed.SelfURL, feed.ParseStatus, pq.Array(feed.ParseMessages))
+	return err
+}
+
+// SetParseStatus records the outcome of a fetch that did not get as far as
+// ingesting the feed.
+func (d *DB) SetParseStatus(ctx context.Context, feedID, status string, messages []string) error {
+	_, err := d.ExecContext(ctx, `UPDATE feeds SET parse_status = $2, parse_messages = $3 WHERE id = $1`,
+		feedID, status, pq.Array(messages))
+	return err
+}
+
+// updateArticle keeps the stored version of the article as a revision and
+// overwrites it with the new content.
+func updateArticle(ctx context.Context, tx *sql.Tx, article *models.Article) error {
+	revisionID, err := uuid.New()
+	if err != nil {
+		return err
+	}
+
+	_, err = execTx(ctx, tx, `INSERT INTO article_revisions (id, article_id, title, published_at, description, content, content_hash)
+      SELECT $1, id, title, published_at, description, content, content_hash FROM articles WHERE id = $2`, revisionID, article.ID)
+	if err != nil {
+		return err
+	}
+
+	// Text extracted from the page stays unless the article moved to another
+	// page, in which case it is extracted again.
+	_, err = execTx(ctx, tx, `UPDATE articles SET title = $1, link = $2, published_at = $3, description = $4, content = $5,
+        plain_text = CASE WHEN link = $2 AND full_content <> '' THEN plain_text ELSE $6 END,
+        full_content = CASE WHEN link = $2 THEN full_content ELSE '' END,
+        extracted_at = CASE WHEN link = $2 THEN extracted_at END,
+        extract_attempts = CASE WHEN link = $2 THEN extract_attempts ELSE 0 END,
+        authors = $7, categories = $8, content_hash = $9, simhash = $10, updated_at = CURRENT_TIMESTAMP
+      WHERE id = $11`, article.Title, article.Link, article.PublishedAt, article.Description, article.Content,
+		article.PlainText, pq.Array(article.Authors), pq.Array(article.Categories), article.ContentHash,
+		nullSimHash(article.SimHash), article.ID)
+	return err
+}
+
+// nullSimHash stores a missing fingerprint as NULL so that it never matches.
+func nullSimHash(h uint64) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(h), Valid: h != 0}
+}
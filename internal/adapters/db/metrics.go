@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"rsshub/pkg/tracing"
+)
+
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rsshub_db_query_duration_seconds",
+	Help:    "Time taken by database queries, by statement type; transaction covers whole ingest transactions.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"statement"})
+
+// observe records the duration of a query started at start.
+func observe(query string, start time.Time) {
+	queryDuration.WithLabelValues(statement(query)).Observe(time.Since(start).Seconds())
+}
+
+// statement returns the kind of a query: select, insert, update, delete,
+// with, transaction or other.
+func statement(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	switch kind := strings.ToLower(fields[0]); kind {
+	case "select", "insert", "update", "delete", "with", "transaction":
+		return kind
+	}
+	return "other"
+}
+
+// maxStatementLength caps the query text recorded in spans.
+const maxStatementLength = 2000
+
+// startSpan starts a span for a query, as a child of the span in ctx.
+// Spans of queries returning rows end before the rows are read.
+func startSpan(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	if !tracing.Enabled() {
+		return ctx, nil
+	}
+	text := strings.Join(strings.Fields(query), " ")
+	if len(text) > maxStatementLength {
+		text = text[:maxStatementLength] + "..."
+	}
+	return tracing.StartClient(ctx, "db."+statement(query), "db.system", "postgresql", "db.statement", text)
+}
+
+// The query methods of sql.DB are shadowed to time every query, and to
+// trace those given a context. Queries made in transactions are timed as a
+// whole by the transaction, and traced with execTx and queryTx.
+
+func (d *DB) Exec(query string, args ...any) (sql.Result, error) {
+	defer observe(query, time.Now())
+	return d.DB.Exec(query, args...)
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer observe(query, time.Now())
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+	res, err := d.DB.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+	return res, err
+}
+
+func (d *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	defer observe(query, time.Now())
+	return d.DB.Query(query, args...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer observe(query, time.Now())
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
+
+func (d *DB) QueryRow(query string, args ...any) *sql.Row {
+	defer observe(query, time.Now())
+	return d.DB.QueryRow(query, args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	defer observe(query, time.Now())
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+	return d.DB.QueryRowContext(ctx, query, args...)
+}
+
+// execTx runs a statement of a transaction in a span.
+func execTx(ctx context.Context, tx *sql.Tx, query string, args ...any) (sql.Result, error) {
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+	res, err := tx.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+	return res, err
+}
+
+// queryTx runs a query of a transaction in a span.
+func queryTx(ctx context.Context, tx *sql.Tx, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+	rows, err := tx.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
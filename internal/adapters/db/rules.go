@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/uuid"
+)
+
+// ruleColumns is the column list, on rules aliased as r left joined to
+// feeds f, read by scanRule.
+const ruleColumns = `r.id, r.created_at, r.name, r.query, COALESCE(r.feed_id::text, ''), COALESCE(f.name, ''), r.tag,
+        r.sinks, r.secret,
+        (SELECT COUNT(*) FROM matches m WHERE m.rule_id = r.id),
+        (SELECT MAX(m.created_at) FROM matches m WHERE m.rule_id = r.id)`
+
+func scanRule(row scanner) (models.Rule, error) {
+	var r models.Rule
+	var lastMatch sql.NullTime
+	if err := row.Scan(&r.ID, &r.CreatedAt, &r.Name, &r.Query, &r.FeedID, &r.FeedName, &r.Tag, pq.Array(&r.Sinks), &r.Secret,
+		&r.Matches, &lastMatch); err != nil {
+		return r, err
+	}
+	if lastMatch.Valid {
+		r.LastMatch = lastMatch.Time
+	}
+	return r, nil
+}
+
+func scanRules(rows *sql.Rows) ([]models.Rule, error) {
+	defer rows.Close()
+	var rules []models.Rule
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// AddRule stores an alert rule.
+func (d *DB) AddRule(rule *models.Rule) error {
+	id, err := uuid.New()
+	if err != nil {
+		return err
+	}
+	rule.ID = id
+	feedID := sql.NullString{String: rule.FeedID, Valid: rule.FeedID != ""}
+	_, err = d.Exec(`INSERT INTO rules (id, name, query, feed_id, tag, sinks, secret) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		rule.ID, rule.Name, rule.Query, feedID, rule.Tag, pq.Array(append([]string{}, rule.Sinks...)), rule.Secret)
+	return err
+}
+
+// GetRule returns the named rule, or sql.ErrNoRows if there is none.
+func (d *DB) GetRule(name string) (*models.Rule, error) {
+	r, err := scanRule(d.QueryRow(`SELECT `+ruleColumns+` FROM rules r LEFT JOIN feeds f ON f.id = r.feed_id
+      WHERE r.name = $1`, name))
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListRules returns all rules, by name.
+func (d *DB) ListRules() ([]models.Rule, error) {
+	rows, err := d.Query(`SELECT ` + ruleColumns + ` FROM rules r LEFT JOIN feeds f ON f.id = r.feed_id ORDER BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	return scanRules(rows)
+}
+
+// DeleteRule deletes the named rule and its matches. It returns
+// sql.ErrNoRows if there is no such rule.
+func (d *DB) DeleteRule(name string) error {
+	res, err := d.Exec(`DELETE FROM rules WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetFeedRules returns the rules that apply to a feed: its own, those of
+// its tags and those of all feeds.
+func (d *DB) GetFeedRules(ctx context.Context, feed models.Feed) ([]models.Rule, error) {
+	rows, err := d.QueryContext(ctx, `SELECT `+ruleColumns+` FROM rules r LEFT JOIN feeds f ON f.id = r.feed_id
+      WHERE r.feed_id = $1 OR r.tag = ANY($2) OR (r.feed_id IS NULL AND r.tag = '')`,
+		feed.ID, pq.Array(append([]string{}, feed.Tags...)))
+	if err != nil {
+		return nil, err
+	}
+	return scanRules(rows)
+}
+
+// RecordMatches records that articles matched a rule and returns the IDs of
+// those that had not matched it before.
+func (d *DB) RecordMatches(ctx context.Context, ruleID string, articleIDs []string) ([]string, error) {
+	rows, err := d.QueryContext(ctx, `INSERT INTO matches (rule_id, article_id)
+      SELECT $1, unnest($2::uuid[])
+      ON CONFLICT (rule_id, article_id) DO NOTHING
+      RETURNING article_id`, ruleID, pq.Array(articleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recorded []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		recorded = append(recorded, id)
+	}
+	return recorded, rows.Err()
+}
+
+// GetRuleArticles returns the latest articles in the scope of a rule, for
+// testing it against past articles.
+func (d *DB) GetRuleArticles(rule models.Rule, limit int) ([]models.Article, error) {
+	rows, err := d.Query(`SELECT `+articleColumns+`
+      FROM articles a
+      JOIN feeds f ON a.feed_id = f.id
+      WHERE ($1 = '' OR f.id::text = $1) AND ($2 = '' OR $2 = ANY(f.tags))
+      ORDER BY a.published_at DESC
+      LIMIT $3`, rule.FeedID, rule.Tag, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	for rows.Next() {
+		var a models.Article
+		if err := scanArticle(rows, &a); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
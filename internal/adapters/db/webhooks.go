@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/uuid"
+)
+
+// webhookColumns is the column list, on webhooks aliased as w left joined
+// to feeds f, read by scanWebhook.
+const webhookColumns = `w.id, w.created_at, w.url, w.secret, COALESCE(w.feed_id::text, ''), COALESCE(f.name, ''), w.tag`
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var w models.Webhook
+	err := row.Scan(&w.ID, &w.CreatedAt, &w.URL, &w.Secret, &w.FeedID, &w.FeedName, &w.Tag)
+	return w, err
+}
+
+// AddWebhook stores a webhook for a feed or a tag.
+func (d *DB) AddWebhook(hook *models.Webhook) error {
+	id, err := uuid.New()
+	if err != nil {
+		return err
+	}
+	hook.ID = id
+	feedID := sql.NullString{String: hook.FeedID, Valid: hook.FeedID != ""}
+	_, err = d.Exec(`INSERT INTO webhooks (id, url, secret, feed_id, tag) VALUES ($1, $2, $3, $4, $5)`,
+		hook.ID, hook.URL, hook.Secret, feedID, hook.Tag)
+	return err
+}
+
+// ListWebhooks returns all webhooks, oldest first.
+func (d *DB) ListWebhooks() ([]models.Webhook, error) {
+	rows, err := d.Query(`SELECT ` + webhookColumns + ` FROM webhooks w LEFT JOIN feeds f ON f.id = w.feed_id
+      ORDER BY w.created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []models.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook deletes a webhook and its delivery log. It returns
+// sql.ErrNoRows if there is no such webhook.
+func (d *DB) DeleteWebhook(id string) error {
+	res, err := d.Exec(`DELETE FROM webhooks WHERE id::text = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetFeedWebhooks returns the webhooks of a feed and of its tags.
+func (d *DB) GetFeedWebhooks(ctx context.Context, feed models.Feed) ([]models.Webhook, error) {
+	rows, err := d.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks w LEFT JOIN feeds f ON f.id = w.feed_id
+      WHERE w.feed_id = $1 OR w.tag = ANY($2)`, feed.ID, pq.Array(append([]string{}, feed.Tags...)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []models.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+// QueueDelivery stores the first attempt of a delivery, to be made as soon
+// as possible, with the payload to post.
+func (d *DB) QueueDelivery(ctx context.Context, delivery models.WebhookDelivery, payload []byte) error {
+	_, err := d.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, articles, payload)
+      VALUES ($1, $2, $3, $4, $5, $6)`,
+		delivery.WebhookID, delivery.DeliveryID, delivery.Event, delivery.Attempt, delivery.Articles, payload)
+	if err != nil {
+		return fmt.Errorf("error queueing webhook delivery: %v", err)
+	}
+	return nil
+}
+
+// ClaimDelivery marks the pending attempt that has been due longest as
+// being made and returns it. It returns sql.ErrNoRows if no attempt is due.
+func (d *DB) ClaimDelivery(ctx context.Context) (*models.QueuedDelivery, error) {
+	var q models.QueuedDelivery
+	err := d.QueryRowContext(ctx, `WITH claimed AS (
+        UPDATE webhook_deliveries SET state = 'sending', attempt_at = CURRENT_TIMESTAMP
+        WHERE id = (SELECT id FROM webhook_deliveries
+          WHERE state = 'pending' AND attempt_at <= CURRENT_TIMESTAMP
+          ORDER BY attempt_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+        RETURNING id, created_at, webhook_id, delivery_id, event, attempt, state, attempt_at, articles, payload)
+      SELECT c.id, c.created_at, c.webhook_id, w.url, w.secret, c.delivery_id, c.event, c.attempt, c.state, c.attempt_at,
+        c.articles, c.payload
+      FROM claimed c JOIN webhooks w ON w.id = c.webhook_id`).Scan(&q.ID, &q.CreatedAt, &q.WebhookID, &q.WebhookURL,
+		&q.Webhook.Secret, &q.DeliveryID, &q.Event, &q.Attempt, &q.State, &q.AttemptAt, &q.Articles, &q.Payload)
+	if err != nil {
+		return nil, err
+	}
+	q.Webhook.ID, q.Webhook.URL = q.WebhookID, q.WebhookURL
+	return &q, nil
+}
+
+// FinishDelivery records the outcome of an attempt, whose payload is no
+// longer kept. If its state is retrying the next attempt is queued, due
+// after retryIn.
+func (d *DB) FinishDelivery(ctx context.Context, delivery models.WebhookDelivery, retryIn time.Duration) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if delivery.State == models.DeliveryRetrying {
+		_, err := execTx(ctx, tx, `INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, articles, payload, attempt_at)
+        SELECT webhook_id, delivery_id, event, attempt + 1, articles, payload, CURRENT_TIMESTAMP + make_interval(secs => $2)
+        FROM webhook_deliveries WHERE id = $1`, delivery.ID, retryIn.Seconds())
+		if err != nil {
+			return fmt.Errorf("error queueing webhook retry: %v", err)
+		}
+	}
+	_, err = execTx(ctx, tx, `UPDATE webhook_deliveries SET state = $2, status_code = $3, error = $4, duration_ms = $5,
+        payload = NULL
+      WHERE id = $1`, delivery.ID, delivery.State, delivery.StatusCode, delivery.Error, delivery.Duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("error logging webhook delivery: %v", err)
+	}
+	return tx.Commit()
+}
+
+// ResetDeliveries makes the attempts that were under way when the fetch
+// process last stopped pending again.
+func (d *DB) ResetDeliveries(ctx context.Context) error {
+	_, err := d.ExecContext(ctx, `UPDATE webhook_deliveries SET state = 'pending' WHERE state = 'sending'`)
+	return err
+}
+
+// GetDeliveries returns the latest delivery attempts, made or pending, of
+// one webhook if webhookID is set.
+func (d *DB) GetDeliveries(webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := d.Query(`SELECT l.id, l.created_at, l.webhook_id, w.url, l.delivery_id, l.event, l.attempt, l.state,
+        l.attempt_at, l.status_code, l.error, l.duration_ms, l.articles
+      FROM webhook_deliveries l JOIN webhooks w ON w.id = l.webhook_id
+      WHERE $1 = '' OR l.webhook_id::text = $1
+      ORDER BY l.attempt_at DESC LIMIT $2`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var l models.WebhookDelivery
+		var ms int64
+		if err := rows.Scan(&l.ID, &l.CreatedAt, &l.WebhookID, &l.WebhookURL, &l.DeliveryID, &l.Event, &l.Attempt, &l.State,
+			&l.AttemptAt, &l.StatusCode, &l.Error, &ms, &l.Articles); err != nil {
+			return nil, err
+		}
+		l.Duration = time.Duration(ms) * time.Millisecond
+		deliveries = append(deliveries, l)
+	}
+	return deliveries, rows.Err()
+}
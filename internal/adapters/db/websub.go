@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	models "rsshub/internal/domain"
+)
+
+// subscriptionColumns is the column list read by scanSubscription.
+const subscriptionColumns = `feed_id, hub, topic, secret, state, expires_at, updated_at, requested_at`
+
+func scanSubscription(row scanner) (models.Subscription, error) {
+	var s models.Subscription
+	var expires, requested sql.NullTime
+	if err := row.Scan(&s.FeedID, &s.Hub, &s.Topic, &s.Secret, &s.State, &expires, &s.UpdatedAt, &requested); err != nil {
+		return s, err
+	}
+	if expires.Valid {
+		s.ExpiresAt = expires.Time
+	}
+	if requested.Valid {
+		s.RequestedAt = requested.Time
+	}
+	return s, nil
+}
+
+// GetFeedByID returns the feed with the given ID, or sql.ErrNoRows if there
+// is none.
+func (d *DB) GetFeedByID(ctx context.Context, id string) (*models.Feed, error) {
+	f, err := scanFeed(d.QueryRowContext(ctx, `SELECT `+feedColumns+` FROM feeds WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// GetSubscription returns the WebSub subscription of a feed, or
+// sql.ErrNoRows if it has none.
+func (d *DB) GetSubscription(ctx context.Context, feedID string) (*models.Subscription, error) {
+	s, err := scanSubscription(d.QueryRowContext(ctx, `SELECT `+subscriptionColumns+`
+      FROM websub_subscriptions WHERE feed_id = $1`, feedID))
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveSubscription creates or replaces the subscription of a feed, which
+// becomes pending until the hub verifies it, and marks it as requested.
+func (d *DB) SaveSubscription(ctx context.Context, s models.Subscription) error {
+	_, err := d.ExecContext(ctx, `INSERT INTO websub_subscriptions (feed_id, hub, topic, secret, state, requested_at)
+      VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+      ON CONFLICT (feed_id) DO UPDATE SET hub = $2, topic = $3, secret = $4, state = $5,
+        updated_at = CURRENT_TIMESTAMP, requested_at = CURRENT_TIMESTAMP`,
+		s.FeedID, s.Hub, s.Topic, s.Secret, s.State)
+	return err
+}
+
+// SetSubscriptionRequested marks a subscription as requested again from the
+// hub, as when it is renewed.
+func (d *DB) SetSubscriptionRequested(ctx context.Context, feedID string) error {
+	_, err := d.ExecContext(ctx, `UPDATE websub_subscriptions SET requested_at = CURRENT_TIMESTAMP WHERE feed_id = $1`, feedID)
+	return err
+}
+
+// SetSubscriptionState changes the state of a subscription, which answers
+// its request. Active subscriptions expire after lease; it is ignored for
+// other states.
+func (d *DB) SetSubscriptionState(ctx context.Context, feedID, state string, lease time.Duration) error {
+	_, err := d.ExecContext(ctx, `UPDATE websub_subscriptions SET state = $2, updated_at = CURRENT_TIMESTAMP,
+        expires_at = CASE WHEN $2 = 'active' THEN CURRENT_TIMESTAMP + make_interval(secs => $3) END,
+        requested_at = NULL
+      WHERE feed_id = $1`, feedID, state, lease.Seconds())
+	return err
+}
+
+// GetExpiringSubscriptions returns the active subscriptions that expire
+// within the given time.
+func (d *DB) GetExpiringSubscriptions(ctx context.Context, within time.Duration) ([]models.Subscription, error) {
+	rows, err := d.QueryContext(ctx, `SELECT `+subscriptionColumns+`
+      FROM websub_subscriptions
+      WHERE state = 'active' AND expires_at < CURRENT_TIMESTAMP + make_interval(secs => $1)
+      ORDER BY expires_at`, within.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []models.Subscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
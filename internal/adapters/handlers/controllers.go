@@ -3,55 +3,148 @@ package handler
 import (
 	"bufio"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"net"
+	"net/http"
+	"net/mail"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"rsshub/internal/adapters/db"
 	"rsshub/internal/app/aggregator"
+	"rsshub/internal/app/digest"
+	"rsshub/internal/app/extractor"
+	"rsshub/internal/app/filter"
+	"rsshub/internal/app/health"
+	"rsshub/internal/app/route"
+	"rsshub/internal/app/rss"
+	"rsshub/internal/app/rule"
+	"rsshub/internal/app/source"
+	"rsshub/internal/app/webhook"
+	"rsshub/internal/app/websub"
 	"rsshub/internal/config"
 	models "rsshub/internal/domain"
+	"rsshub/pkg/charset"
+	"rsshub/pkg/logger"
+	"rsshub/pkg/mailer"
+	"rsshub/pkg/sanitize"
+	"rsshub/pkg/tracing"
 )
 
 const sockAddr = "./rsshub.sock"
 
 func HandleFetch(cfg *config.Config, database *db.DB) {
+	tracer, err := newTracer(cfg)
+	if err != nil {
+		logger.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	if tracer != nil {
+		defer func() {
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancelShutdown()
+			if err := tracer.Shutdown(shutdownCtx); err != nil {
+				logger.Error("Failed to flush traces", "error", err)
+			}
+		}()
+	}
 	agg := aggregator.NewAggregator(database, cfg.TimerInterval, cfg.WorkersCount)
+	agg.UseSources(newSources(cfg))
 	listener, err := net.Listen("unix", "/tmp/rsshub.sock")
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logger.Error("Failed to listen on control socket", "error", err)
+		os.Exit(1)
 	}
 	defer listener.Close()
 
-	// Start the aggregator
+	// Components are stopped in the reverse order they were started in,
+	// also when starting one of them fails, by deferring each stop as soon
+	// as the component is running. The aggregator starts last, so that it
+	// stops first and no ingest hook runs on a stopped component.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err = agg.Start(ctx)
+	hooks := newIngestHooks(cfg, database, agg)
+	stopHooks, err := hooks.start(ctx, false)
 	if err != nil {
-		log.Printf("Failed to start aggregator: %v", err)
+		logger.Error("Failed to start ingest hooks", "error", err)
+		return
+	}
+	defer stopHooks()
+
+	// Digests are only sent on schedule if there is an SMTP server to send
+	// them through.
+	if cfg.SMTPAddr != "" {
+		digests := digest.NewSender(database, newMailer(cfg))
+		if err := digests.Start(ctx); err != nil {
+			logger.Error("Failed to start digests", "error", err)
+			return
+		}
+		defer stop("digests", digests.Stop)
+	}
+
+	// The HTTP server serves metrics and health checks. WebSub also needs it for its callbacks,
+	// and a public URL that hubs can reach it at.
+	checker := health.NewChecker(database, agg)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/healthz", health.Handler(checker.Live))
+	mux.Handle("/readyz", health.Handler(checker.Ready))
+	if hooks.push != nil {
+		mux.Handle(websub.CallbackPath, hooks.push.Handler())
+	}
+	if cfg.HTTPAddr != "" {
+		server := &http.Server{Addr: cfg.HTTPAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("HTTP server error", "error", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancelShutdown()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				logger.Error("Failed to stop HTTP server", "error", err)
+			}
+		}()
+	}
+
+	if err := agg.Start(ctx); err != nil {
+		logger.Error("Failed to start aggregator", "error", err)
 		return
 	}
+	defer func() {
+		if err := agg.Stop(); err != nil {
+			logger.Error("Failed to stop aggregator", "error", err)
+			return
+		}
+		logger.Info("Graceful shutdown: aggregator stopped")
+	}()
 
-	fmt.Printf("The background process for fetching feeds has started (interval = %s, workers = %d)\n",
-		cfg.TimerInterval, cfg.WorkersCount)
+	logger.Info("The background process for fetching feeds has started", "interval", cfg.TimerInterval,
+		"workers", cfg.WorkersCount)
 
 	// Handle control commands via socket
 	go func() {
 		for {
 			conn, err := listener.Accept()
 			if err != nil {
-				log.Printf("Accept error: %v", err)
+				logger.Error("Accept error", "error", err)
 				return
 			}
-			go handleControl(conn, agg, cfg)
+			go handleControl(conn, agg, checker, cfg)
 		}
 	}()
 
@@ -59,15 +152,121 @@ func HandleFetch(cfg *config.Config, database *db.DB) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
+}
+
+// stop stops a background component, logging failures as
+// "Failed to stop what".
+func stop(what string, fn func() error) {
+	if err := fn(); err != nil {
+		logger.Error("Failed to stop "+what, "error", err)
+	}
+}
+
+// ingestHooks are the components that act on the articles the aggregator
+// stores: full-text extraction, webhooks, alert rules and, when the fetch
+// process serves WebSub callbacks at a public URL, WebSub subscriptions.
+type ingestHooks struct {
+	ext    *extractor.Extractor
+	hooks  *webhook.Dispatcher
+	alerts *rule.Engine
+	push   *websub.Manager // nil without an HTTP address and a public URL
+}
+
+// newIngestHooks creates the components and registers them as ingest hooks
+// of agg, so that feeds fetched and feeds ingested from stdin go through
+// the same pipeline.
+func newIngestHooks(cfg *config.Config, database *db.DB, agg *aggregator.Aggregator) *ingestHooks {
+	h := &ingestHooks{
+		ext:    extractor.NewExtractor(database, cfg.ExtractInterval, cfg.ExtractWorkers, cfg.ExtractGap, cfg.ExtractHostDelay),
+		hooks:  webhook.NewDispatcher(database),
+		alerts: rule.NewEngine(database, newMailer(cfg)),
+	}
+	agg.OnIngest(func(feed models.Feed, result *models.IngestResult) {
+		if feed.FullText && len(result.Inserted)+len(result.Updated) > 0 {
+			h.ext.Notify()
+		}
+	})
+	agg.OnIngest(h.hooks.Notify)
+	agg.OnIngest(h.alerts.Check)
+	if cfg.HTTPAddr != "" && cfg.PublicURL != "" {
+		h.push = websub.NewManager(database, cfg.PublicURL, agg)
+		agg.OnIngest(func(feed models.Feed, result *models.IngestResult) {
+			h.push.Check(feed)
+		})
+	}
+	return h
+}
+
+// start starts the components and returns a function that stops them in
+// reverse order. If one fails to start, those already running are stopped.
+// With once, for a process that exits right after ingesting, the work left
+// to the fetch process is not started: articles wait for its extractor and
+// webhook deliveries are queued for its workers.
+func (h *ingestHooks) start(ctx context.Context, once bool) (func(), error) {
+	var stops []func()
+	stopAll := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if !once {
+		if err := h.ext.Start(ctx); err != nil {
+			return nil, fmt.Errorf("extractor: %v", err)
+		}
+		stops = append(stops, func() { stop("extractor", h.ext.Stop) })
+	}
+
+	startHooks := h.hooks.Start
+	if once {
+		startHooks = h.hooks.StartQueueing
+	}
+	if err := startHooks(ctx); err != nil {
+		stopAll()
+		return nil, fmt.Errorf("webhooks: %v", err)
+	}
+	stops = append(stops, func() { stop("webhooks", h.hooks.Stop) })
+
+	if err := h.alerts.Start(ctx); err != nil {
+		stopAll()
+		return nil, fmt.Errorf("alert rules: %v", err)
+	}
+	stops = append(stops, func() { stop("alert rules", h.alerts.Stop) })
+
+	if h.push != nil {
+		if err := h.push.Start(ctx); err != nil {
+			stopAll()
+			return nil, fmt.Errorf("WebSub: %v", err)
+		}
+		stops = append(stops, func() { stop("WebSub", h.push.Stop) })
+	}
+	return stopAll, nil
+}
 
-	err = agg.Stop()
+// newTracer installs a tracer exporting spans as configured, or returns nil
+// if tracing is off.
+func newTracer(cfg *config.Config) (*tracing.Tracer, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.TraceExporter {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		exporter, err = tracing.NewWriterExporter(os.Stdout)
+	case "file":
+		exporter, err = tracing.NewFileExporter(cfg.TraceFile)
+	case "otlp":
+		exporter, err = tracing.NewOTLPExporter(cfg.TraceEndpoint, tracing.ParseHeaders(cfg.TraceHeaders))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, want none, stdout, file or otlp", cfg.TraceExporter)
+	}
 	if err != nil {
-		log.Printf("Failed to stop aggregator: %v", err)
+		return nil, err
 	}
-	fmt.Println("Graceful shutdown: aggregator stopped")
+	logger.Info("Tracing feed fetches", "exporter", cfg.TraceExporter)
+	return tracing.Install("rsshub", exporter), nil
 }
 
-func handleControl(conn net.Conn, agg *aggregator.Aggregator, cfg *config.Config) {
+func handleControl(conn net.Conn, agg *aggregator.Aggregator, checker *health.Checker, cfg *config.Config) {
 	defer conn.Close()
 	reader := bufio.NewReader(conn)
 	cmd, err := reader.ReadString('\n')
@@ -76,6 +275,10 @@ func handleControl(conn net.Conn, agg *aggregator.Aggregator, cfg *config.Config
 	}
 	cmd = strings.TrimSpace(cmd)
 	parts := strings.Fields(cmd)
+	if len(parts) == 1 && parts[0] == "health" {
+		json.NewEncoder(conn).Encode(checker.All(context.Background()))
+		return
+	}
 	if len(parts) != 2 {
 		fmt.Fprint(conn, "Invalid command\n")
 		return
@@ -101,10 +304,8 @@ func handleControl(conn net.Conn, agg *aggregator.Aggregator, cfg *config.Config
 		old := cfg.TimerInterval // Adjust based on where interval is stored
 		agg.SetInterval(d)
 		cfg.TimerInterval = d
-		msg := fmt.Sprintf("[%s] Interval of fetching feeds changed from %s to %s\n",
-			time.Now().Format(time.RFC3339), old.String(), d.String())
-		fmt.Print(msg)        // Log to server stdout
-		fmt.Fprint(conn, msg) // Send to client
+		logger.Info("Interval of fetching feeds changed", "from", old, "to", d)
+		fmt.Fprintf(conn, "Interval of fetching feeds changed from %s to %s\n", old, d)
 	case "set-workers":
 		n, err := strconv.Atoi(parts[1])
 		if err != nil || n <= 0 {
@@ -124,10 +325,8 @@ func handleControl(conn net.Conn, agg *aggregator.Aggregator, cfg *config.Config
 			return
 		}
 		cfg.WorkersCount = n
-		msg := fmt.Sprintf("[%s] Number of workers changed from %d to %d\n",
-			time.Now().Format(time.RFC3339), oldN, n)
-		fmt.Print(msg)        // Log to server stdout
-		fmt.Fprint(conn, msg) // Send to client
+		logger.Info("Number of workers changed", "from", oldN, "to", n)
+		fmt.Fprintf(conn, "Number of workers changed from %d to %d\n", oldN, n)
 	default:
 		fmt.Fprint(conn, "Unknown command\n")
 	}
@@ -135,83 +334,320 @@ func handleControl(conn net.Conn, agg *aggregator.Aggregator, cfg *config.Config
 
 func HandleSetInterval(cfg *config.Config) {
 	if len(os.Args) < 3 {
-		fmt.Printf("[%s] Usage: rsshub set-interval <duration>\n",
-			time.Now().Format(time.RFC3339))
+		fmt.Println("Usage: rsshub set-interval <duration>")
 		return
 	}
 	durStr := os.Args[2]
 	_, err := time.ParseDuration(durStr) // Validate locally first.
 	if err != nil {
-		fmt.Printf("[%s] Invalid duration: %v\n",
-			time.Now().Format(time.RFC3339), err)
+		logger.Error("Invalid duration", "error", err)
 		return
 	}
 	conn, err := net.Dial("unix", "/tmp/rsshub.sock")
 	if err != nil {
-		fmt.Printf("[%s] Background process is not running or failed to connect: %v\n",
-			time.Now().Format(time.RFC3339), err)
+		logger.Error("Background process is not running or failed to connect", "error", err)
 		return
 	}
 	defer conn.Close()
 	fmt.Fprintf(conn, "set-interval %s\n", durStr)
 	response, err := bufio.NewReader(conn).ReadString('\n')
 	if err != nil {
-		fmt.Printf("[%s] Error reading response: %v\n",
-			time.Now().Format(time.RFC3339), err)
+		logger.Error("Error reading response", "error", err)
 	} else {
 		fmt.Print(response) // Print the server's confirmation message.
 	}
 }
 
+// HandleHealth asks the background process for its health report and
+// exits with status 1 if it is unhealthy or not running, so that it can be
+// used as a container health check.
+func HandleHealth() {
+	healthSet := flag.NewFlagSet("health", flag.ExitOnError)
+	asJSON := healthSet.Bool("json", false, "print the report as JSON")
+	healthSet.Parse(os.Args[2:])
+
+	conn, err := net.DialTimeout("unix", "/tmp/rsshub.sock", 5*time.Second)
+	if err != nil {
+		logger.Error("Background process is not running or failed to connect", "error", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprint(conn, "health\n")
+	body, err := io.ReadAll(conn)
+	if err != nil {
+		logger.Error("Error reading response", "error", err)
+		os.Exit(1)
+	}
+	var report health.Report
+	if err := json.Unmarshal(body, &report); err != nil {
+		logger.Error("Invalid health report", "error", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		os.Stdout.Write(body)
+	} else {
+		fmt.Printf("Status: %s\n", report.Status)
+		for _, c := range report.Checks {
+			mark := "ok"
+			if !c.OK {
+				mark = "FAIL"
+			}
+			fmt.Printf("  %-10s %-4s  %s\n", c.Name, mark, c.Message)
+		}
+	}
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
 func HandleSetWorkers(cfg *config.Config) {
 	if len(os.Args) < 3 {
-		fmt.Printf("[%s] Usage: rsshub set-workers <count>\n",
-			time.Now().Format(time.RFC3339))
+		fmt.Println("Usage: rsshub set-workers <count>")
 		return
 	}
 	countStr := os.Args[2]
 	newWorkers, err := strconv.Atoi(countStr)
 	if err != nil || newWorkers <= 0 {
-		fmt.Printf("[%s] Invalid number of workers\n",
-			time.Now().Format(time.RFC3339))
+		logger.Error("Invalid number of workers")
 		return
 	}
 	conn, err := net.Dial("unix", "/tmp/rsshub.sock")
 	if err != nil {
-		fmt.Printf("[%s] Background process is not running or failed to connect: %v\n",
-			time.Now().Format(time.RFC3339), err)
+		logger.Error("Background process is not running or failed to connect", "error", err)
 		return
 	}
 	defer conn.Close()
 	fmt.Fprintf(conn, "set-workers %d\n", newWorkers)
 	response, err := bufio.NewReader(conn).ReadString('\n')
 	if err != nil {
-		fmt.Printf("[%s] Error reading response: %v\n",
-			time.Now().Format(time.RFC3339), err)
+		logger.Error("Error reading response", "error", err)
 	} else {
 		fmt.Print(response)
 	}
 }
 
 // Implement other handlers as per your existing code
-func HandleAdd(database *db.DB) {
+func HandleAdd(cfg *config.Config, database *db.DB) {
 	addSet := flag.NewFlagSet("add", flag.ExitOnError)
 	name := addSet.String("name", "", "feed name")
 	url := addSet.String("url", "", "feed url")
+	fullText := addSet.Bool("full-text", false, "download and extract the full text of each article")
+	tags := addSet.String("tags", "", "comma-separated tags")
+	force := addSet.Bool("force", false, "add the feed even if it cannot be fetched or has problems")
 	addSet.Parse(os.Args[2:])
 
-	if *name == "" || *url == "" {
-		fmt.Printf("[%s] Missing name or url\n", time.Now().Format(time.RFC3339))
+	if *url == "" {
+		logger.Error("Missing url")
+		return
+	}
+
+	feedURL := *url
+	candidates, err := discover(cfg, *url)
+	switch {
+	case err != nil && !*force:
+		logger.Error("Error finding feed; use --force to add it anyway", "error", err)
+		return
+	case err != nil:
+		logger.Warn("Error finding feed", "error", err)
+	default:
+		chosen := chooseCandidate(candidates)
+		if chosen.URL != *url {
+			logger.Info("Found feed", "url", chosen.URL)
+		}
+		feedURL = chosen.URL
+		if report := rss.Validate(chosen.Feed); !report.OK() {
+			printReport(report)
+			if !*force {
+				logger.Error("Feed has problems, not added; use --force to add it anyway")
+				return
+			}
+		}
+		if *name == "" {
+			*name = feedName(chosen)
+		}
+	}
+	if *name == "" {
+		logger.Error("Missing name")
 		return
 	}
 
-	feed := &models.Feed{Name: *name, URL: *url}
-	err := database.AddFeed(feed)
+	feed := &models.Feed{Name: *name, URL: feedURL, FullText: *fullText, Tags: parseTags(strings.Split(*tags, ","))}
+	err = database.AddFeed(feed)
 	if err != nil {
-		fmt.Printf("[%s] Error adding feed: %v\n", time.Now().Format(time.RFC3339), err)
+		logger.Error("Error adding feed", "error", err)
 	} else {
-		fmt.Printf("[%s] Feed added successfully\n", time.Now().Format(time.RFC3339))
+		logger.Info("Feed added", "feed", feed.Name)
+	}
+}
+
+func HandlePreview(cfg *config.Config) {
+	previewSet := flag.NewFlagSet("preview", flag.ExitOnError)
+	url := previewSet.String("url", "", "feed or site url")
+	previewSet.Parse(os.Args[2:])
+
+	if *url == "" {
+		logger.Error("Missing url")
+		return
+	}
+
+	candidates, err := discover(cfg, *url)
+	if err != nil {
+		logger.Error("Error fetching feed", "error", err)
+		os.Exit(1)
+	}
+	for i, c := range candidates {
+		if i > 0 {
+			fmt.Println()
+		}
+		printField("URL", c.URL)
+		printReport(rss.Validate(c.Feed))
+	}
+}
+
+// newSources returns the feed sources enabled by the configuration, by
+// URL scheme.
+func newSources(cfg *config.Config) *source.Registry {
+	sources := source.NewRegistry()
+	sources.Register("http", source.HTTP{})
+	sources.Register("https", source.HTTP{})
+	sources.Register("file", source.File{})
+	sources.Register(route.Scheme, route.Source{Dir: cfg.RoutesDir})
+	if cfg.ExecSources {
+		sources.Register("exec", source.Exec{})
+	}
+	return sources
+}
+
+// discover finds the feeds at url. Web pages are searched for feeds; URLs
+// of other sources, such as route://, are fetched from their source.
+func discover(cfg *config.Config, url string) ([]rss.Candidate, error) {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return rss.Discover(context.Background(), url)
+	}
+	feed, err := newSources(cfg).Fetch(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	return []rss.Candidate{{URL: url, Feed: feed}}, nil
+}
+
+func HandleRoute(cfg *config.Config) {
+	if len(os.Args) == 3 && os.Args[2] == "list" {
+		names, err := route.List(cfg.RoutesDir)
+		if err != nil {
+			logger.Error("Error listing routes", "error", err)
+			return
+		}
+		fmt.Printf("# Routes in %s\n", cfg.RoutesDir)
+		for i, name := range names {
+			rule, err := route.Load(cfg.RoutesDir, name)
+			if err != nil {
+				fmt.Printf("%d. %s\n   Error: %v\n", i+1, name, err)
+				continue
+			}
+			fmt.Printf("%d. %s\n   URL: %s\n   Feed: %s://%s\n", i+1, name, rule.URL, route.Scheme, name)
+		}
+		return
+	}
+	if len(os.Args) != 4 || os.Args[2] != "test" {
+		fmt.Println("Usage: rsshub route list | rsshub route test <name>")
+		return
+	}
+
+	rule, err := route.Load(cfg.RoutesDir, os.Args[3])
+	if err != nil {
+		logger.Error("Error loading route", "error", err)
+		os.Exit(1)
+	}
+	feed, err := rule.Fetch(context.Background())
+	if err != nil {
+		logger.Error("Error fetching route", "error", err)
+		os.Exit(1)
+	}
+	printReport(rss.Validate(feed))
+	for i, item := range feed.Items {
+		fmt.Printf("%d. [%s] %s\n   %s\n", i+1, item.PublishedAt.Format("2006-01-02"), item.Title, item.Link)
+	}
+}
+
+// printReport prints the summary of a feed and the problems found in it.
+func printReport(r rss.Report) {
+	printField("Format", r.Format)
+	printField("Title", r.Title)
+	printField("Items", strconv.Itoa(r.Items))
+	if r.Items > 0 {
+		printField("Date range", r.Oldest.Format("2006-01-02 15:04")+" to "+r.Newest.Format("2006-01-02 15:04"))
+	}
+	if r.OK() {
+		fmt.Println("No problems found")
+		return
+	}
+	fmt.Println("Problems:")
+	for _, p := range r.Problems {
+		fmt.Printf("  - %s\n", p)
+	}
+}
+
+// chooseCandidate returns the only feed found, or asks which one to add
+// when a site has several. Without an answer the first one is taken.
+func chooseCandidate(candidates []rss.Candidate) rss.Candidate {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	fmt.Println("Several feeds were found:")
+	for i, c := range candidates {
+		title := c.Title
+		if title == "" {
+			title = c.Feed.Title
+		}
+		fmt.Printf("%d. %s (%s, %d items)\n   %s\n", i+1, title, c.Feed.Format, len(c.Feed.Items), c.URL)
+	}
+	fmt.Print("Add which one? [1]: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if n, err := strconv.Atoi(strings.TrimSpace(answer)); err == nil && n >= 1 && n <= len(candidates) {
+		return candidates[n-1]
+	}
+	return candidates[0]
+}
+
+// parseTags lowercases and trims tags, dropping empty ones and repeats.
+func parseTags(values []string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		tag := strings.ToLower(strings.TrimSpace(v))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// feedName derives a default feed name from the feed's title, e.g.
+// "The Go Blog" becomes "the-go-blog".
+func feedName(c rss.Candidate) string {
+	title := c.Feed.Title
+	if title == "" {
+		title = c.Title
 	}
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
 }
 
 func HandleList(database *db.DB) {
@@ -219,17 +655,131 @@ func HandleList(database *db.DB) {
 	num := listSet.Int("num", 0, "number of feeds")
 	listSet.Parse(os.Args[2:])
 	if *num < 0 {
-		fmt.Printf("[%s] This number %v cannot be negative \n", time.Now().Format(time.RFC3339), *num)
+		logger.Error("Number cannot be negative", "num", *num)
 		os.Exit(1)
 	}
 	feeds, err := database.ListFeeds(*num)
 	if err != nil {
-		fmt.Printf("[%s] Error listing feeds: %v\n", time.Now().Format(time.RFC3339), err)
+		logger.Error("Error listing feeds", "error", err)
 		return
 	}
-	fmt.Printf("[%s] # Available RSS Feeds\n", time.Now().Format(time.RFC3339))
+	fmt.Println("# Available RSS Feeds")
 	for i, f := range feeds {
-		fmt.Printf("%d. Name: %s\n   URL: %s\n   Added: %s\n", i+1, f.Name, f.URL, f.CreatedAt.Format("2006-01-02 15:04"))
+		fmt.Printf("%d. Name: %s\n   URL: %s\n", i+1, f.Name, f.URL)
+		if f.Title != "" {
+			fmt.Printf("   Title: %s\n", f.Title)
+		}
+		if f.SiteURL != "" {
+			fmt.Printf("   Site: %s\n", f.SiteURL)
+		}
+		if len(f.Tags) > 0 {
+			fmt.Printf("   Tags: %s\n", strings.Join(f.Tags, ", "))
+		}
+		if f.ParseStatus != "" && f.ParseStatus != models.ParseOK {
+			fmt.Printf("   Status: %s\n", parseStatusLabels[f.ParseStatus])
+		}
+		fmt.Printf("   Added: %s\n", f.CreatedAt.Format("2006-01-02 15:04"))
+	}
+}
+
+// parseStatusLabels describe the parse statuses of feeds.
+var parseStatusLabels = map[string]string{
+	models.ParseOK:       "ok",
+	models.ParseWarnings: "parsed with warnings",
+	models.ParseFailed:   "failed",
+}
+
+func HandleFeed(database *db.DB) {
+	if len(os.Args) == 5 && os.Args[2] == "full-text" {
+		setFullText(database, os.Args[3], os.Args[4])
+		return
+	}
+	if len(os.Args) >= 4 && os.Args[2] == "tags" {
+		setTags(database, os.Args[3], parseTags(os.Args[4:]))
+		return
+	}
+	if len(os.Args) < 4 || os.Args[2] != "show" {
+		fmt.Println("Usage: rsshub feed show <name> | rsshub feed full-text <name> on|off | rsshub feed tags <name> [tag...]")
+		return
+	}
+
+	feed, err := database.GetFeed(os.Args[3])
+	if err == sql.ErrNoRows {
+		logger.Error("Feed not found", "feed", os.Args[3])
+		return
+	}
+	if err != nil {
+		logger.Error("Error getting feed", "error", err)
+		return
+	}
+
+	printField("Name", feed.Name)
+	printField("URL", feed.URL)
+	printField("Title", feed.Title)
+	printField("Site", feed.SiteURL)
+	printField("Description", feed.Description)
+	printField("Language", feed.Language)
+	printField("Icon", feed.IconURL)
+	printField("Generator", feed.Generator)
+	if !feed.LastBuildDate.IsZero() {
+		printField("Last build", feed.LastBuildDate.Format("2006-01-02 15:04"))
+	}
+	if feed.TTL > 0 {
+		printField("TTL", fmt.Sprintf("%d minutes", feed.TTL))
+	}
+	if feed.FullText {
+		printField("Full text", "on")
+	}
+	printField("Tags", strings.Join(feed.Tags, ", "))
+	printField("WebSub hub", feed.HubURL)
+	printField("Added", feed.CreatedAt.Format("2006-01-02 15:04"))
+	if !feed.UpdatedAt.IsZero() {
+		printField("Last fetched", feed.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	printField("Status", parseStatusLabels[feed.ParseStatus])
+	for _, msg := range feed.ParseMessages {
+		fmt.Printf("  - %s\n", msg)
+	}
+}
+
+func setFullText(database *db.DB, name, mode string) {
+	if mode != "on" && mode != "off" {
+		fmt.Println("Usage: rsshub feed full-text <name> on|off")
+		return
+	}
+	err := database.SetFeedFullText(name, mode == "on")
+	if err == sql.ErrNoRows {
+		logger.Error("Feed not found", "feed", name)
+		return
+	}
+	if err != nil {
+		logger.Error("Error updating feed", "error", err)
+		return
+	}
+	logger.Info("Full-text extraction changed", "feed", name, "full_text", mode)
+}
+
+func setTags(database *db.DB, name string, tags []string) {
+	err := database.SetFeedTags(name, tags)
+	if err == sql.ErrNoRows {
+		logger.Error("Feed not found", "feed", name)
+		return
+	}
+	if err != nil {
+		logger.Error("Error updating feed", "error", err)
+		return
+	}
+	if len(tags) == 0 {
+		logger.Info("Tags removed", "feed", name)
+		return
+	}
+	logger.Info("Tags set", "feed", name, "tags", strings.Join(tags, ", "))
+}
+
+// printField prints a "Label: value" line, skipping empty values.
+func printField(label, value string) {
+	if value != "" {
+		fmt.Printf("%s: %s\n", label, value)
 	}
 }
 
@@ -239,15 +789,15 @@ func HandleDelete(database *db.DB) {
 	delSet.Parse(os.Args[2:])
 
 	if *name == "" {
-		fmt.Printf("[%s] Missing name\n", time.Now().Format(time.RFC3339))
+		logger.Error("Missing name")
 		return
 	}
 
 	err := database.DeleteFeed(*name)
 	if err != nil {
-		fmt.Printf("[%s] Error deleting feed: %v\n", time.Now().Format(time.RFC3339), err)
+		logger.Error("Error deleting feed", "error", err)
 	} else {
-		fmt.Printf("[%s] Feed deleted successfully\n", time.Now().Format(time.RFC3339))
+		logger.Info("Feed deleted", "feed", *name)
 	}
 }
 
@@ -255,20 +805,940 @@ func HandleArticles(database *db.DB) {
 	artSet := flag.NewFlagSet("articles", flag.ExitOnError)
 	feedName := artSet.String("feed-name", "", "feed name")
 	num := artSet.Int("num", 3, "number of articles")
+	history := artSet.Bool("history", false, "show earlier revisions of updated articles")
+	collapse := artSet.Bool("collapse", false, "show one entry per story across feeds")
+	author := artSet.String("author", "", "only articles by this author")
+	category := artSet.String("category", "", "only articles in this category")
+	body := artSet.Bool("body", false, "show the article text")
+	format := artSet.String("format", "text", "body format: text or markdown")
 	artSet.Parse(os.Args[2:])
 
-	if *feedName == "" {
-		fmt.Printf("[%s] Missing feed-name\n", time.Now().Format(time.RFC3339))
+	if *format != "text" && *format != "markdown" {
+		logger.Error("Unknown format, expected text or markdown", "format", *format)
+		return
+	}
+
+	filter := models.ArticleFilter{FeedName: *feedName, Author: *author, Category: *category, Limit: *num}
+
+	title := *feedName
+	if title == "" {
+		title = "all feeds"
+	}
+
+	if *collapse {
+		stories, err := database.GetStories(filter)
+		if err != nil {
+			logger.Error("Error getting stories", "error", err)
+			return
+		}
+		fmt.Printf("Feed: %s\n", title)
+		for i, s := range stories {
+			fmt.Printf("%d. [%s] %s\n   %s\n", i+1, s.Article.PublishedAt.Format("2006-01-02"), s.Article.Title, s.Article.Link)
+			if s.Articles > 1 {
+				fmt.Printf("   Sources: %s (%d articles)\n", strings.Join(s.Sources, ", "), s.Articles)
+			}
+		}
 		return
 	}
 
-	articles, err := database.GetArticles(*feedName, *num)
+	articles, err := database.GetArticles(filter)
 	if err != nil {
-		fmt.Printf("[%s] Error getting articles: %v\n", time.Now().Format(time.RFC3339), err)
+		logger.Error("Error getting articles", "error", err)
+		return
+	}
+	if err := database.LoadMedia(articles); err != nil {
+		logger.Error("Error getting article media", "error", err)
 		return
 	}
-	fmt.Printf("[%s] Feed: %s\n", time.Now().Format(time.RFC3339), *feedName)
+	fmt.Printf("Feed: %s\n", title)
 	for i, a := range articles {
 		fmt.Printf("%d. [%s] %s\n   %s\n", i+1, a.PublishedAt.Format("2006-01-02"), a.Title, a.Link)
+		if len(a.Authors) > 0 {
+			fmt.Printf("   By: %s\n", strings.Join(a.Authors, ", "))
+		}
+		if len(a.Categories) > 0 {
+			fmt.Printf("   Categories: %s\n", strings.Join(a.Categories, ", "))
+		}
+		for _, m := range a.Media {
+			fmt.Printf("   Media: %s", m.URL)
+			if m.MIMEType != "" {
+				fmt.Printf(" (%s)", m.MIMEType)
+			}
+			if m.Duration > 0 {
+				fmt.Printf(" %s", time.Duration(m.Duration)*time.Second)
+			}
+			fmt.Println()
+		}
+		if *body {
+			printBody(a, *format)
+		}
+		if a.UpdatedAt.IsZero() {
+			continue
+		}
+		fmt.Printf("   Updated: %s\n", a.UpdatedAt.Format("2006-01-02 15:04"))
+		if !*history {
+			continue
+		}
+		revisions, err := database.GetArticleRevisions(a.ID)
+		if err != nil {
+			logger.Error("Error getting revisions", "error", err)
+			continue
+		}
+		for _, r := range revisions {
+			fmt.Printf("   - [%s] %s\n", r.CreatedAt.Format("2006-01-02 15:04"), r.Title)
+		}
+	}
+}
+
+// printBody prints the article text indented under its entry. Markdown is
+// rendered from the stored HTML, preferring text extracted from the page;
+// text uses the plain text kept at ingest.
+func printBody(a models.Article, format string) {
+	text := a.PlainText
+	if format == "markdown" {
+		source := a.FullContent
+		if source == "" {
+			source = a.Content
+		}
+		if source == "" {
+			source = a.Description
+		}
+		text = sanitize.Markdown(source)
+	}
+	if text == "" {
+		return
+	}
+	fmt.Println()
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Println(strings.TrimRight("   "+line, " "))
+	}
+	fmt.Println()
+}
+
+// HandleIngest stores a feed read from stdin, RSS, Atom or JSON, in an
+// existing feed, through the same pipeline as fetched feeds.
+func HandleIngest(cfg *config.Config, database *db.DB) {
+	ingestSet := flag.NewFlagSet("ingest", flag.ExitOnError)
+	feedName := ingestSet.String("feed-name", "", "feed to store the articles in")
+	ingestSet.Parse(os.Args[2:])
+
+	if *feedName == "" {
+		logger.Error("Missing feed-name")
+		os.Exit(1)
+	}
+	feed, err := database.GetFeed(*feedName)
+	if err == sql.ErrNoRows {
+		logger.Error("Feed not found", "feed", *feedName)
+		os.Exit(1)
+	}
+	if err != nil {
+		logger.Error("Error getting feed", "error", err)
+		os.Exit(1)
+	}
+
+	body, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		logger.Error("Error reading stdin", "error", err)
+		os.Exit(1)
+	}
+	if body, err = charset.ToUTF8(body, ""); err != nil {
+		logger.Error("Error decoding feed", "error", err)
+		os.Exit(1)
+	}
+	base := ""
+	if strings.HasPrefix(feed.URL, "http://") || strings.HasPrefix(feed.URL, "https://") {
+		base = feed.URL
+	}
+	parsed, err := rss.Parse(body, base)
+	if err != nil {
+		logger.Error("Error parsing feed", "error", err)
+		os.Exit(1)
+	}
+	for _, warning := range parsed.Warnings {
+		logger.Warn("Feed has problems", "problem", warning)
+	}
+
+	agg := aggregator.NewAggregator(database, cfg.TimerInterval, cfg.WorkersCount)
+	hooks := newIngestHooks(cfg, database, agg)
+	stopHooks, err := hooks.start(context.Background(), true)
+	if err != nil {
+		logger.Error("Failed to start ingest hooks", "error", err)
+		os.Exit(1)
+	}
+	result, err := agg.Ingest(context.Background(), *feed, parsed)
+	hooks.alerts.Flush()
+	stopHooks()
+	if err != nil {
+		logger.Error("Error ingesting feed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Feed ingested", "feed", feed.Name, "new", len(result.Inserted), "updated", len(result.Updated), "filtered", result.Filtered)
+}
+
+func HandleExport(database *db.DB) {
+	exportSet := flag.NewFlagSet("export", flag.ExitOnError)
+	feedName := exportSet.String("feed-name", "", "feed name")
+	num := exportSet.Int("num", 20, "number of articles")
+	exportSet.Parse(os.Args[2:])
+
+	if *feedName == "" {
+		logger.Error("Missing feed-name")
+		os.Exit(1)
+	}
+
+	feed, err := database.GetFeed(*feedName)
+	if err != nil {
+		logger.Error("Error getting feed", "error", err)
+		os.Exit(1)
+	}
+	articles, err := database.GetArticles(models.ArticleFilter{FeedName: *feedName, Limit: *num})
+	if err == nil {
+		err = database.LoadMedia(articles)
+	}
+	if err != nil {
+		logger.Error("Error getting articles", "error", err)
+		os.Exit(1)
+	}
+
+	out, err := rss.Render(*feed, articles)
+	if err != nil {
+		logger.Error("Error rendering feed", "error", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(out)
+}
+
+func HandleWebhook(database *db.DB) {
+	if len(os.Args) >= 3 {
+		switch os.Args[2] {
+		case "add":
+			addWebhook(database)
+			return
+		case "list":
+			listWebhooks(database)
+			return
+		case "delete":
+			if len(os.Args) == 4 {
+				deleteWebhook(database, os.Args[3])
+				return
+			}
+		case "log":
+			webhookLog(database)
+			return
+		}
+	}
+	fmt.Println("Usage: rsshub webhook add --url <url> --feed-name <name> | --tag <tag> [--secret <secret>] | " +
+		"rsshub webhook list | rsshub webhook delete <id> | rsshub webhook log [--id <id>] [--num <n>]")
+}
+
+func addWebhook(database *db.DB) {
+	addSet := flag.NewFlagSet("webhook add", flag.ExitOnError)
+	url := addSet.String("url", "", "URL events are posted to")
+	feedName := addSet.String("feed-name", "", "send events of this feed")
+	tag := addSet.String("tag", "", "send events of feeds with this tag")
+	secret := addSet.String("secret", "", "key payloads are signed with; generated if not set")
+	addSet.Parse(os.Args[3:])
+
+	if !strings.HasPrefix(*url, "http://") && !strings.HasPrefix(*url, "https://") {
+		logger.Error("Missing or invalid url")
+		return
+	}
+	if (*feedName == "") == (*tag == "") {
+		logger.Error("Set either feed-name or tag")
+		return
+	}
+
+	hook := models.Webhook{URL: *url, Secret: *secret, Tag: strings.ToLower(strings.TrimSpace(*tag))}
+	if *feedName != "" {
+		feed, err := database.GetFeed(*feedName)
+		if err == sql.ErrNoRows {
+			logger.Error("Feed not found", "feed", *feedName)
+			return
+		}
+		if err != nil {
+			logger.Error("Error getting feed", "error", err)
+			return
+		}
+		hook.FeedID = feed.ID
+	}
+	if hook.Secret == "" {
+		s, err := webhook.NewSecret()
+		if err != nil {
+			logger.Error("Error creating secret", "error", err)
+			return
+		}
+		hook.Secret = s
+	}
+
+	if err := database.AddWebhook(&hook); err != nil {
+		logger.Error("Error adding webhook", "error", err)
+		return
+	}
+	logger.Info("Webhook added", "id", hook.ID)
+	if *secret == "" {
+		fmt.Printf("Secret: %s\n", hook.Secret)
+	}
+}
+
+func listWebhooks(database *db.DB) {
+	hooks, err := database.ListWebhooks()
+	if err != nil {
+		logger.Error("Error listing webhooks", "error", err)
+		return
+	}
+	fmt.Println("# Webhooks")
+	for i, h := range hooks {
+		fmt.Printf("%d. ID: %s\n   URL: %s\n", i+1, h.ID, h.URL)
+		if h.FeedName != "" {
+			fmt.Printf("   Feed: %s\n", h.FeedName)
+		}
+		if h.Tag != "" {
+			fmt.Printf("   Tag: %s\n", h.Tag)
+		}
+		if h.Secret != "" {
+			fmt.Printf("   Signed: yes\n")
+		}
+		fmt.Printf("   Added: %s\n", h.CreatedAt.Format("2006-01-02 15:04"))
+	}
+}
+
+func deleteWebhook(database *db.DB, id string) {
+	err := database.DeleteWebhook(id)
+	if err == sql.ErrNoRows {
+		logger.Error("Webhook not found", "id", id)
+		return
+	}
+	if err != nil {
+		logger.Error("Error deleting webhook", "error", err)
+		return
+	}
+	logger.Info("Webhook deleted", "id", id)
+}
+
+func webhookLog(database *db.DB) {
+	logSet := flag.NewFlagSet("webhook log", flag.ExitOnError)
+	id := logSet.String("id", "", "only deliveries to this webhook")
+	num := logSet.Int("num", 20, "number of deliveries")
+	logSet.Parse(os.Args[3:])
+
+	deliveries, err := database.GetDeliveries(*id, *num)
+	if err != nil {
+		logger.Error("Error getting deliveries", "error", err)
+		return
+	}
+	fmt.Println("# Webhook deliveries")
+	for i, l := range deliveries {
+		var result string
+		switch l.State {
+		case models.DeliveryPending, models.DeliverySending:
+			result = l.State
+		case models.DeliveryDelivered:
+			result = fmt.Sprintf("ok in %s", l.Duration)
+		default:
+			result = fmt.Sprintf("%s in %s (%s)", l.Error, l.Duration, l.State)
+		}
+		fmt.Printf("%d. %s %s to %s\n   Delivery: %s (attempt %d, %d articles)\n   Result: %s\n",
+			i+1, l.AttemptAt.Format("2006-01-02 15:04:05"), l.Event, l.WebhookURL, l.DeliveryID, l.Attempt, l.Articles,
+			result)
+	}
+}
+
+func newMailer(cfg *config.Config) *mailer.Mailer {
+	return &mailer.Mailer{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.SMTPFrom}
+}
+
+// stringList is a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func HandleRule(database *db.DB) {
+	if len(os.Args) >= 3 {
+		switch os.Args[2] {
+		case "add":
+			addRule(database)
+			return
+		case "list":
+			listRules(database)
+			return
+		case "test":
+			testRule(database)
+			return
+		case "delete":
+			if len(os.Args) == 4 {
+				deleteRule(database, os.Args[3])
+				return
+			}
+		}
+	}
+	fmt.Println("Usage: rsshub rule add --name <name> --query <query> [--feed-name <name> | --tag <tag>] [--sink <sink>...] | " +
+		"rsshub rule list | rsshub rule test <name> | --query <query> [--num <n>] | rsshub rule delete <name>")
+}
+
+func addRule(database *db.DB) {
+	var sinks stringList
+	addSet := flag.NewFlagSet("rule add", flag.ExitOnError)
+	name := addSet.String("name", "", "rule name")
+	query := addSet.String("query", "", "articles to alert on")
+	feedName := addSet.String("feed-name", "", "only articles of this feed")
+	tag := addSet.String("tag", "", "only articles of feeds with this tag")
+	secret := addSet.String("secret", "", "key webhook payloads are signed with; generated if not set")
+	addSet.Var(&sinks, "sink", "where to send alerts: stdout, a webhook URL or mailto:address; may be repeated")
+	addSet.Parse(os.Args[3:])
+
+	if *name == "" || *query == "" {
+		logger.Error("Missing name or query")
+		return
+	}
+	if *feedName != "" && *tag != "" {
+		logger.Error("Set feed-name or tag, not both")
+		return
+	}
+	if _, err := rule.Compile(*query); err != nil {
+		logger.Error("Invalid query", "error", err)
+		return
+	}
+	if len(sinks) == 0 {
+		sinks = stringList{rule.SinkStdout}
+	}
+	hasWebhook := false
+	for _, sink := range sinks {
+		if err := rule.CheckSink(sink); err != nil {
+			logger.Error("Invalid sink", "error", err)
+			return
+		}
+		hasWebhook = hasWebhook || strings.HasPrefix(sink, "http")
+	}
+
+	r := models.Rule{Name: *name, Query: *query, Tag: strings.ToLower(strings.TrimSpace(*tag)), Sinks: sinks, Secret: *secret}
+	if *feedName != "" {
+		feed, err := database.GetFeed(*feedName)
+		if err == sql.ErrNoRows {
+			logger.Error("Feed not found", "feed", *feedName)
+			return
+		}
+		if err != nil {
+			logger.Error("Error getting feed", "error", err)
+			return
+		}
+		r.FeedID = feed.ID
+	}
+	if hasWebhook && r.Secret == "" {
+		s, err := webhook.NewSecret()
+		if err != nil {
+			logger.Error("Error creating secret", "error", err)
+			return
+		}
+		r.Secret = s
+	}
+
+	if err := database.AddRule(&r); err != nil {
+		logger.Error("Error adding rule", "error", err)
+		return
+	}
+	logger.Info("Rule added", "rule", r.Name)
+	if hasWebhook && *secret == "" {
+		fmt.Printf("Secret: %s\n", r.Secret)
+	}
+}
+
+func listRules(database *db.DB) {
+	rules, err := database.ListRules()
+	if err != nil {
+		logger.Error("Error listing rules", "error", err)
+		return
+	}
+	fmt.Println("# Alert Rules")
+	for i, r := range rules {
+		fmt.Printf("%d. Name: %s\n   Query: %s\n", i+1, r.Name, r.Query)
+		switch {
+		case r.FeedName != "":
+			fmt.Printf("   Feed: %s\n", r.FeedName)
+		case r.Tag != "":
+			fmt.Printf("   Tag: %s\n", r.Tag)
+		default:
+			fmt.Printf("   Feeds: all\n")
+		}
+		fmt.Printf("   Sinks: %s\n", strings.Join(r.Sinks, ", "))
+		fmt.Printf("   Matches: %d", r.Matches)
+		if !r.LastMatch.IsZero() {
+			fmt.Printf(", last %s", r.LastMatch.Format("2006-01-02 15:04"))
+		}
+		fmt.Println()
+	}
+}
+
+// testRule runs a stored rule, or a query, against past articles in its
+// scope without recording matches or sending alerts.
+func testRule(database *db.DB) {
+	var args []string
+	var r models.Rule
+	if len(os.Args) >= 4 && !strings.HasPrefix(os.Args[3], "-") {
+		stored, err := database.GetRule(os.Args[3])
+		if err == sql.ErrNoRows {
+			logger.Error("Rule not found", "rule", os.Args[3])
+			return
+		}
+		if err != nil {
+			logger.Error("Error getting rule", "error", err)
+			return
+		}
+		r = *stored
+		args = os.Args[4:]
+	} else {
+		args = os.Args[3:]
+	}
+
+	testSet := flag.NewFlagSet("rule test", flag.ExitOnError)
+	query := testSet.String("query", r.Query, "articles to look for")
+	feedName := testSet.String("feed-name", "", "only articles of this feed")
+	tag := testSet.String("tag", r.Tag, "only articles of feeds with this tag")
+	num := testSet.Int("num", 1000, "number of past articles to test")
+	testSet.Parse(args)
+
+	compiled, err := rule.Compile(*query)
+	if err != nil {
+		logger.Error("Invalid query", "error", err)
+		return
+	}
+	r.Tag = strings.ToLower(strings.TrimSpace(*tag))
+	if *feedName != "" {
+		feed, err := database.GetFeed(*feedName)
+		if err == sql.ErrNoRows {
+			logger.Error("Feed not found", "feed", *feedName)
+			return
+		}
+		if err != nil {
+			logger.Error("Error getting feed", "error", err)
+			return
+		}
+		r.FeedID = feed.ID
+	}
+
+	articles, err := database.GetRuleArticles(r, *num)
+	if err != nil {
+		logger.Error("Error getting articles", "error", err)
+		return
+	}
+	matches := 0
+	for _, a := range articles {
+		if !compiled.Match(&a) {
+			continue
+		}
+		matches++
+		fmt.Printf("%d. %s\n   %s\n   Published: %s\n", matches, a.Title, a.Link, a.PublishedAt.Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("%d of %d articles match\n", matches, len(articles))
+}
+
+func deleteRule(database *db.DB, name string) {
+	err := database.DeleteRule(name)
+	if err == sql.ErrNoRows {
+		logger.Error("Rule not found", "rule", name)
+		return
+	}
+	if err != nil {
+		logger.Error("Error deleting rule", "error", err)
+		return
+	}
+	logger.Info("Rule deleted", "rule", name)
+}
+
+func HandleDigest(cfg *config.Config, database *db.DB) {
+	if len(os.Args) >= 3 {
+		switch os.Args[2] {
+		case "add":
+			addDigest(database)
+			return
+		case "list":
+			listDigests(database)
+			return
+		case "send":
+			sendDigests(cfg, database)
+			return
+		case "delete":
+			if len(os.Args) == 4 {
+				deleteDigest(database, os.Args[3])
+				return
+			}
+		}
+	}
+	fmt.Println("Usage: rsshub digest add --name <name> --to <addresses> [--schedule daily|weekly] [--at HH:MM] [--day <weekday>] " +
+		"[--group feed|tag] [--feed-name <name> | --tag <tag>] | rsshub digest list | rsshub digest send [name] [--dry-run] | " +
+		"rsshub digest delete <name>")
+}
+
+// weekdays maps day names, and their first three letters, to weekdays.
+var weekdays = func() map[string]time.Weekday {
+	m := make(map[string]time.Weekday)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		m[name] = d
+		m[name[:3]] = d
+	}
+	return m
+}()
+
+func addDigest(database *db.DB) {
+	addSet := flag.NewFlagSet("digest add", flag.ExitOnError)
+	name := addSet.String("name", "", "digest name")
+	to := addSet.String("to", "", "comma-separated recipient addresses")
+	schedule := addSet.String("schedule", models.DigestDaily, "daily or weekly")
+	at := addSet.String("at", "08:00", "local time to send at, as HH:MM")
+	day := addSet.String("day", "monday", "day of the week weekly digests are sent on")
+	group := addSet.String("group", models.DigestByFeed, "group articles by feed or tag")
+	feedName := addSet.String("feed-name", "", "only articles of this feed")
+	tag := addSet.String("tag", "", "only articles of feeds with this tag")
+	addSet.Parse(os.Args[3:])
+
+	if *name == "" {
+		logger.Error("Missing name")
+		return
+	}
+	var recipients []string
+	for _, addr := range strings.Split(*to, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(addr); err != nil {
+			logger.Error("Invalid address", "address", addr, "error", err)
+			return
+		}
+		recipients = append(recipients, addr)
+	}
+	if len(recipients) == 0 {
+		logger.Error("Missing to")
+		return
+	}
+	if *schedule != models.DigestDaily && *schedule != models.DigestWeekly {
+		logger.Error("Schedule must be daily or weekly")
+		return
+	}
+	if _, _, err := digest.ParseTime(*at); err != nil {
+		logger.Error("Invalid time", "error", err)
+		return
+	}
+	weekday, ok := weekdays[strings.ToLower(*day)]
+	if !ok {
+		logger.Error("Unknown day", "day", *day)
+		return
+	}
+	if *group != models.DigestByFeed && *group != models.DigestByTag {
+		logger.Error("Group must be feed or tag")
+		return
+	}
+	if *feedName != "" && *tag != "" {
+		logger.Error("Set feed-name or tag, not both")
+		return
+	}
+
+	d := models.Digest{Name: *name, Recipients: recipients, Schedule: *schedule, SendTime: *at, Weekday: weekday,
+		GroupBy: *group, Tag: strings.ToLower(strings.TrimSpace(*tag))}
+	if *feedName != "" {
+		feed, err := database.GetFeed(*feedName)
+		if err == sql.ErrNoRows {
+			logger.Error("Feed not found", "feed", *feedName)
+			return
+		}
+		if err != nil {
+			logger.Error("Error getting feed", "error", err)
+			return
+		}
+		d.FeedID = feed.ID
+	}
+	if err := database.AddDigest(&d); err != nil {
+		logger.Error("Error adding digest", "error", err)
+		return
+	}
+	logger.Info("Digest added", "digest", d.Name, "next", digest.Next(d, time.Now()).Format("Mon 2006-01-02 15:04"))
+}
+
+func listDigests(database *db.DB) {
+	digests, err := database.ListDigests(context.Background())
+	if err != nil {
+		logger.Error("Error listing digests", "error", err)
+		return
+	}
+	fmt.Println("# Digests")
+	for i, d := range digests {
+		when := fmt.Sprintf("daily at %s", d.SendTime)
+		if d.Schedule == models.DigestWeekly {
+			when = fmt.Sprintf("weekly on %s at %s", d.Weekday, d.SendTime)
+		}
+		fmt.Printf("%d. Name: %s\n   To: %s\n   Schedule: %s, grouped by %s\n", i+1, d.Name,
+			strings.Join(d.Recipients, ", "), when, d.GroupBy)
+		if d.FeedName != "" {
+			fmt.Printf("   Feed: %s\n", d.FeedName)
+		}
+		if d.Tag != "" {
+			fmt.Printf("   Tag: %s\n", d.Tag)
+		}
+		last := d.LastSentAt
+		if !last.IsZero() {
+			fmt.Printf("   Last sent: %s\n", last.Local().Format("2006-01-02 15:04"))
+		} else {
+			last = d.CreatedAt
+		}
+		fmt.Printf("   Next: %s\n", digest.Next(d, last.Local()).Format("Mon 2006-01-02 15:04"))
+	}
+}
+
+// sendDigests sends one digest, or all of them, right away, whatever their
+// schedule. With --dry-run, the plain text of the digests is printed
+// instead.
+func sendDigests(cfg *config.Config, database *db.DB) {
+	var args []string
+	var name string
+	if len(os.Args) >= 4 && !strings.HasPrefix(os.Args[3], "-") {
+		name = os.Args[3]
+		args = os.Args[4:]
+	} else {
+		args = os.Args[3:]
+	}
+	sendSet := flag.NewFlagSet("digest send", flag.ExitOnError)
+	dryRun := sendSet.Bool("dry-run", false, "print digests instead of sending them")
+	sendSet.Parse(args)
+
+	var digests []models.Digest
+	if name != "" {
+		d, err := database.GetDigest(name)
+		if err == sql.ErrNoRows {
+			logger.Error("Digest not found", "digest", name)
+			return
+		}
+		if err != nil {
+			logger.Error("Error getting digest", "error", err)
+			return
+		}
+		digests = append(digests, *d)
+	} else {
+		var err error
+		digests, err = database.ListDigests(context.Background())
+		if err != nil {
+			logger.Error("Error listing digests", "error", err)
+			return
+		}
+	}
+
+	sender := digest.NewSender(database, newMailer(cfg))
+	for _, d := range digests {
+		if *dryRun {
+			msg, err := sender.Preview(context.Background(), d)
+			if err != nil {
+				logger.Error("Error building digest", "digest", d.Name, "error", err)
+				continue
+			}
+			if msg == nil {
+				logger.Info("No new articles for digest", "digest", d.Name)
+				continue
+			}
+			fmt.Printf("To: %s\nSubject: %s\n\n%s\n", strings.Join(msg.To, ", "), msg.Subject, msg.Text)
+			continue
+		}
+		n, err := sender.Send(context.Background(), d)
+		if err != nil {
+			logger.Error("Error sending digest", "digest", d.Name, "error", err)
+			continue
+		}
+		if n == 0 {
+			logger.Info("No new articles for digest", "digest", d.Name)
+			continue
+		}
+		logger.Info("Digest sent", "digest", d.Name, "articles", n, "to", strings.Join(d.Recipients, ", "))
+	}
+}
+
+func deleteDigest(database *db.DB, name string) {
+	err := database.DeleteDigest(name)
+	if err == sql.ErrNoRows {
+		logger.Error("Digest not found", "digest", name)
+		return
+	}
+	if err != nil {
+		logger.Error("Error deleting digest", "error", err)
+		return
+	}
+	logger.Info("Digest deleted", "digest", name)
+}
+
+func HandleFilter(cfg *config.Config, database *db.DB) {
+	if len(os.Args) >= 3 {
+		switch os.Args[2] {
+		case "add":
+			addFilter(database)
+			return
+		case "list":
+			listFilters(database)
+			return
+		case "test":
+			testFilters(cfg, database)
+			return
+		case "delete":
+			if len(os.Args) == 4 {
+				deleteFilter(database, os.Args[3])
+				return
+			}
+		}
+	}
+	fmt.Println("Usage: rsshub filter add --feed-name <name> --include <regex> | --exclude <regex> [--field title|category] | " +
+		"--min-length <n> | --rewrite-title <regex> --replace <text> | --strip-params <regex> | " +
+		"rsshub filter list --feed-name <name> | rsshub filter test --feed-name <name> | rsshub filter delete <id>")
+}
+
+// filterFeed returns the feed named by the feed-name flag, printing why if
+// there is none.
+func filterFeed(database *db.DB, name string) *models.Feed {
+	if name == "" {
+		logger.Error("Missing feed-name")
+		return nil
+	}
+	feed, err := database.GetFeed(name)
+	if err == sql.ErrNoRows {
+		logger.Error("Feed not found", "feed", name)
+		return nil
+	}
+	if err != nil {
+		logger.Error("Error getting feed", "error", err)
+		return nil
+	}
+	return feed
+}
+
+func addFilter(database *db.DB) {
+	addSet := flag.NewFlagSet("filter add", flag.ExitOnError)
+	feedName := addSet.String("feed-name", "", "feed whose items are filtered")
+	include := addSet.String("include", "", "keep only items matching this regular expression")
+	exclude := addSet.String("exclude", "", "drop items matching this regular expression")
+	field := addSet.String("field", models.FilterTitle, "what include and exclude match: title or category")
+	minLength := addSet.Int("min-length", 0, "drop items whose text is shorter than this many characters")
+	rewrite := addSet.String("rewrite-title", "", "regular expression to replace in titles")
+	replace := addSet.String("replace", "", "replacement for rewrite-title; $1 is the first group")
+	strip := addSet.String("strip-params", "", "remove link query parameters whose names match this regular expression")
+	addSet.Parse(os.Args[3:])
+
+	var filters []models.Filter
+	if *include != "" {
+		filters = append(filters, models.Filter{Action: models.FilterInclude, Field: *field, Pattern: *include})
+	}
+	if *exclude != "" {
+		filters = append(filters, models.Filter{Action: models.FilterExclude, Field: *field, Pattern: *exclude})
+	}
+	if *minLength != 0 {
+		filters = append(filters, models.Filter{Action: models.FilterMinLength, MinLength: *minLength})
+	}
+	if *rewrite != "" {
+		filters = append(filters, models.Filter{Action: models.FilterRewriteTitle, Pattern: *rewrite, Replacement: *replace})
+	}
+	if *strip != "" {
+		filters = append(filters, models.Filter{Action: models.FilterStripParams, Pattern: *strip})
+	}
+	if len(filters) != 1 {
+		logger.Error("Give exactly one of include, exclude, min-length, rewrite-title or strip-params")
+		return
+	}
+	if _, err := filter.Compile(filters); err != nil {
+		logger.Error("Invalid filter", "error", err)
+		return
+	}
+
+	feed := filterFeed(database, *feedName)
+	if feed == nil {
+		return
+	}
+	f := filters[0]
+	f.FeedID = feed.ID
+	if err := database.AddFilter(&f); err != nil {
+		logger.Error("Error adding filter", "error", err)
+		return
+	}
+	logger.Info("Filter added", "position", f.Position, "feed", feed.Name, "filter", filter.Describe(f))
+}
+
+func listFilters(database *db.DB) {
+	listSet := flag.NewFlagSet("filter list", flag.ExitOnError)
+	feedName := listSet.String("feed-name", "", "feed name")
+	listSet.Parse(os.Args[3:])
+
+	feed := filterFeed(database, *feedName)
+	if feed == nil {
+		return
+	}
+	filters, err := database.GetFeedFilters(context.Background(), feed.ID)
+	if err != nil {
+		logger.Error("Error listing filters", "error", err)
+		return
+	}
+	fmt.Printf("# Filters of %s\n", feed.Name)
+	for i, f := range filters {
+		fmt.Printf("%d. %s\n   ID: %s\n", i+1, filter.Describe(f), f.ID)
+	}
+}
+
+// testFilters fetches a feed and shows what its filters do to the items,
+// without storing anything.
+func testFilters(cfg *config.Config, database *db.DB) {
+	testSet := flag.NewFlagSet("filter test", flag.ExitOnError)
+	feedName := testSet.String("feed-name", "", "feed name")
+	testSet.Parse(os.Args[3:])
+
+	feed := filterFeed(database, *feedName)
+	if feed == nil {
+		return
+	}
+	filters, err := database.GetFeedFilters(context.Background(), feed.ID)
+	if err != nil {
+		logger.Error("Error listing filters", "error", err)
+		return
+	}
+	pipeline, err := filter.Compile(filters)
+	if err != nil {
+		logger.Error("Invalid filter", "error", err)
+		return
+	}
+	parsed, err := newSources(cfg).Fetch(context.Background(), feed.URL)
+	if err != nil {
+		logger.Error("Error fetching feed", "error", err)
+		return
+	}
+
+	kept := 0
+	for i, article := range parsed.Items {
+		body := article.Content
+		if body == "" {
+			body = article.Description
+		}
+		article.PlainText = sanitize.Text(sanitize.HTML(body, article.Link))
+		title := article.Title
+		keep, by := pipeline.Apply(&article)
+		if !keep {
+			fmt.Printf("%d. %s\n   Dropped: %s\n", i+1, title, filter.Describe(*by))
+			continue
+		}
+		kept++
+		fmt.Printf("%d. %s\n", i+1, article.Title)
+		if article.Title != title {
+			fmt.Printf("   Was: %s\n", title)
+		}
+		fmt.Printf("   %s\n", article.Link)
+	}
+	fmt.Printf("%d of %d items kept by %d filters\n", kept, len(parsed.Items), pipeline.Len())
+}
+
+func deleteFilter(database *db.DB, id string) {
+	err := database.DeleteFilter(id)
+	if err == sql.ErrNoRows {
+		logger.Error("Filter not found", "id", id)
+		return
+	}
+	if err != nil {
+		logger.Error("Error deleting filter", "error", err)
+		return
 	}
+	logger.Info("Filter deleted", "id", id)
 }
@@ -2,31 +2,64 @@ package aggregator
 
 import (
 	"context"
-	"encoding/xml"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"sync"
 	"time"
 
 	"rsshub/internal/adapters/db"
-	"rsshub/internal/app/rss"
+	"rsshub/internal/app/filter"
+	"rsshub/internal/app/source"
 	"rsshub/internal/domain"
+	"rsshub/pkg/logger"
+	"rsshub/pkg/sanitize"
+	"rsshub/pkg/simhash"
+	"rsshub/pkg/tracing"
 )
 
+// storyMaxDistance is the largest SimHash distance, in bits, at which two
+// articles are still considered the same story.
+const storyMaxDistance = 3
+
+// pushedPollInterval is how often feeds whose updates are pushed over
+// WebSub are still polled, in case pushes go missing.
+const pushedPollInterval = time.Hour
+
+// job is a feed handed to a worker, with the span of the tick that claimed
+// it so that the trace of its fetch can link to the tick.
+type job struct {
+	feed domain.Feed
+	tick *tracing.Span
+}
+
 type Aggregator struct {
 	db            *db.DB
 	mu            sync.Mutex
 	interval      time.Duration
 	numWorkers    int
 	ticker        *time.Ticker
-	jobs          chan domain.Feed
+	jobs          chan job
 	wg            sync.WaitGroup
 	ctx           context.Context
 	cancel        context.CancelFunc
 	workerCancels []context.CancelFunc
 	workerDone    chan struct{} // Added to signal worker termination
+	nextWorker    int           // number of the next worker started, for logs
+	ingestHooks   []func(domain.Feed, *domain.IngestResult)
+	sources       *source.Registry
+	log           *slog.Logger
+
+	// statusMu guards what Status reports.
+	statusMu       sync.Mutex
+	started        time.Time
+	statusInterval time.Duration
+	statusWorkers  int
+	lastTick       time.Time
+	lastFetch      time.Time
+	busy           map[int]WorkerStatus
 }
 
 func NewAggregator(db *db.DB, interval time.Duration, numWorkers int) *Aggregator {
@@ -34,11 +67,30 @@ func NewAggregator(db *db.DB, interval time.Duration, numWorkers int) *Aggregato
 		db:         db,
 		interval:   interval,
 		numWorkers: numWorkers,
-		jobs:       make(chan domain.Feed),
+		jobs:       make(chan job),
 		workerDone: make(chan struct{}), // Initialize the workerDone channel
+		sources:    source.NewRegistry(),
+		log:        logger.With("component", "aggregator"),
 	}
 }
 
+// OnIngest registers fn to be called after the articles of a feed have
+// been committed. Hooks run on the worker that processed the feed and must
+// not block for long. Register hooks before Start.
+func (a *Aggregator) OnIngest(fn func(domain.Feed, *domain.IngestResult)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ingestHooks = append(a.ingestHooks, fn)
+}
+
+// UseSources sets the sources feeds are fetched from, by URL scheme.
+// Register sources before Start.
+func (a *Aggregator) UseSources(sources *source.Registry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sources = sources
+}
+
 func (a *Aggregator) Start(parentCtx context.Context) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -47,6 +99,15 @@ func (a *Aggregator) Start(parentCtx context.Context) error {
 	}
 	a.ctx, a.cancel = context.WithCancel(parentCtx)
 	a.ticker = time.NewTicker(a.interval)
+	fetchInterval.Set(a.interval.Seconds())
+	workersConfigured.Set(float64(a.numWorkers))
+	a.setStatus(func() {
+		a.started = time.Now()
+		a.statusInterval = a.interval
+		a.statusWorkers = a.numWorkers
+		a.lastTick, a.lastFetch = time.Time{}, time.Time{}
+		a.busy = make(map[int]WorkerStatus)
+	})
 	a.wg.Add(1)
 	go a.fetchLoop()
 	a.startWorkers(a.numWorkers)
@@ -66,6 +127,7 @@ func (a *Aggregator) Stop() error {
 	a.wg.Wait()
 	a.ticker = nil
 	a.workerCancels = nil
+	a.setStatus(func() { a.started = time.Time{} })
 	return nil
 }
 
@@ -89,6 +151,8 @@ func (a *Aggregator) SetInterval(d time.Duration) {
 	}
 	a.interval = d
 	a.ticker.Reset(d)
+	fetchInterval.Set(d.Seconds())
+	a.setStatus(func() { a.statusInterval = d })
 }
 
 func (a *Aggregator) Resize(workers int) error {
@@ -111,6 +175,8 @@ func (a *Aggregator) Resize(workers int) error {
 		a.startWorkers(workers - a.numWorkers)
 	}
 	a.numWorkers = workers
+	workersConfigured.Set(float64(workers))
+	a.setStatus(func() { a.statusWorkers = workers })
 	return nil
 }
 
@@ -121,207 +187,236 @@ func (a *Aggregator) fetchLoop() {
 		case <-a.ctx.Done():
 			return
 		case <-a.ticker.C:
-			a.mu.Lock()
-			limit := a.numWorkers
-			a.mu.Unlock()
-			feeds, err := a.db.GetOutdatedFeeds(limit)
-			if err != nil {
-				log.Printf("[%s] Error fetching outdated feeds: %v\n", time.Now().Format(time.RFC3339), err)
-				continue
-			}
-			for _, feed := range feeds {
-				select {
-				case a.jobs <- feed:
-				case <-a.ctx.Done():
-					return
-				}
+			if !a.tick() {
+				return
 			}
 		}
 	}
 }
 
+// tick claims the feeds fetched longest ago and hands them to the workers.
+// It returns false if the aggregator stopped meanwhile.
+func (a *Aggregator) tick() bool {
+	ticks.Inc()
+	a.mu.Lock()
+	limit := a.numWorkers
+	a.mu.Unlock()
+	// Feeds are traced on their own, linked to the tick that claimed them:
+	// the tick ends before the workers are done with its feeds.
+	ctx, span := tracing.Start(a.ctx, "aggregator.tick", "limit", limit)
+	defer span.End()
+
+	claimCtx, claim := tracing.Start(ctx, "feeds.claim", "limit", limit)
+	feeds, err := a.db.GetOutdatedFeeds(claimCtx, limit, pushedPollInterval)
+	claim.Set("feeds", len(feeds))
+	claim.RecordError(err)
+	claim.End()
+	if err != nil {
+		span.RecordError(err)
+		a.log.Error("Error fetching outdated feeds", "error", err)
+		return true
+	}
+	a.log.Debug("Claimed outdated feeds", "feeds", len(feeds), "limit", limit)
+	for i, feed := range feeds {
+		queueDepth.Set(float64(len(feeds) - i))
+		select {
+		case a.jobs <- job{feed: feed, tick: span}:
+		case <-a.ctx.Done():
+			queueDepth.Set(0)
+			return false
+		}
+	}
+	queueDepth.Set(0)
+	a.setStatus(func() { a.lastTick = time.Now() })
+	return true
+}
+
 func (a *Aggregator) startWorkers(n int) {
 	for i := 0; i < n; i++ {
 		ctx, cancel := context.WithCancel(a.ctx)
 		a.workerCancels = append(a.workerCancels, cancel)
+		a.nextWorker++
 		a.wg.Add(1)
-		go a.worker(ctx)
+		go a.worker(ctx, a.nextWorker)
 	}
 }
 
-func (a *Aggregator) worker(ctx context.Context) {
+func (a *Aggregator) worker(ctx context.Context, id int) {
 	defer a.wg.Done()
+	log := a.log.With("worker", id)
+	log.Debug("Worker started")
+	defer log.Debug("Worker stopped")
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-a.workerDone: // Check for termination signal
 			return
-		case feed, ok := <-a.jobs:
+		case job, ok := <-a.jobs:
 			if !ok {
 				return
 			}
-			if err := a.processFeed(feed); err != nil {
-				log.Printf("[%s] Error processing feed %s: %v\n", time.Now().Format(time.RFC3339), feed.URL, err)
+			feed := job.feed
+			workersActive.Inc()
+			start := time.Now()
+			a.setStatus(func() { a.busy[id] = WorkerStatus{Worker: id, Feed: feed.Name, URL: feed.URL, Since: start} })
+			feedLog := log.With("feed", feed.Name, "feed_id", feed.ID, "url", feed.URL)
+			feedCtx, span := tracing.StartLinked(ctx, "feed.process", job.tick,
+				"feed.name", feed.Name, "feed.id", feed.ID, "url", feed.URL, "worker", id)
+			ingested, err := a.processFeed(feedCtx, feedLog, feed)
+			span.RecordError(err)
+			span.End()
+			duration := time.Since(start)
+			result := resultSuccess
+			if err != nil {
+				result = resultFailure
+				feedLog.Error("Error processing feed", "duration", duration, "error", err)
+			} else {
+				feedLog.Info("Feed fetched", "new", len(ingested.Inserted), "updated", len(ingested.Updated),
+					"filtered", ingested.Filtered, "duration", duration)
 			}
+			fetchDuration.WithLabelValues(result).Observe(duration.Seconds())
+			feedFetches.WithLabelValues(feed.Name, result).Inc()
+			workersActive.Dec()
+			a.setStatus(func() {
+				delete(a.busy, id)
+				a.lastFetch = time.Now()
+			})
 		}
 	}
 }
 
-func (a *Aggregator) processFeed(feed domain.Feed) error {
-	// First try to parse as RSS using your existing function
-	rssFeed, err := rss.FetchAndParse(feed.URL)
-	if err == nil && len(rssFeed.Channel.Item) > 0 {
-		// Process as RSS feed
-		for _, item := range rssFeed.Channel.Item {
-			pubDate, err := time.Parse(time.RFC1123, item.PubDate)
-			if err != nil {
-				log.Printf("[%s] Error parsing date %s: %v\n", time.Now().Format(time.RFC3339), item.PubDate, err)
-				continue
-			}
-
-			article := &domain.Article{
-				Title:       item.Title,
-				Link:        item.Link,
-				Description: item.Description,
-				PublishedAt: pubDate,
-				FeedID:      feed.ID,
-			}
-
-			exists, err := a.db.ArticleExists(feed.ID, item.Link)
-			if err != nil {
-				return fmt.Errorf("error checking article existence: %v", err)
-			}
-			if exists {
-				continue
-			}
-
-			if err := a.db.InsertArticle(article); err != nil {
-				return fmt.Errorf("error inserting article: %v", err)
-			}
-		}
-	} else {
-		// If RSS parsing failed, try Atom format
-		atomFeed, err := fetchAndParseAtom(feed.URL)
-		if err != nil {
-			return fmt.Errorf("error fetching and parsing feed %s as Atom: %v", feed.URL, err)
-		}
-
-		for _, entry := range atomFeed.Entries {
-			link := getAtomLink(entry.Link)
-			description := entry.Summary
-			if description == "" {
-				description = entry.Content
-			}
-			pubDate := entry.Published
-			if pubDate == "" {
-				pubDate = entry.Updated
-			}
-
-			pubTime, err := parseAtomDate(pubDate)
-			if err != nil {
-				log.Printf("[%s] Error parsing date %s: %v\n", time.Now().Format(time.RFC3339), pubDate, err)
-				continue
-			}
-
-			article := &domain.Article{
-				Title:       entry.Title,
-				Link:        link,
-				Description: description,
-				PublishedAt: pubTime,
-				FeedID:      feed.ID,
-			}
-
-			exists, err := a.db.ArticleExists(feed.ID, link)
-			if err != nil {
-				return fmt.Errorf("error checking article existence: %v", err)
-			}
-			if exists {
-				continue
-			}
-
-			if err := a.db.InsertArticle(article); err != nil {
-				return fmt.Errorf("error inserting article: %v", err)
-			}
+// processFeed fetches, parses and ingests a feed, logging to log.
+func (a *Aggregator) processFeed(ctx context.Context, log *slog.Logger, feed domain.Feed) (*domain.IngestResult, error) {
+	a.mu.Lock()
+	sources := a.sources
+	a.mu.Unlock()
+	fetchCtx, span := tracing.Start(ctx, "source.fetch", "url", feed.URL)
+	parsed, err := sources.Fetch(fetchCtx, feed.URL)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
+		if err := a.db.SetParseStatus(ctx, feed.ID, domain.ParseFailed, []string{err.Error()}); err != nil {
+			log.Error("Error saving status of feed", "error", err)
 		}
+		return nil, fmt.Errorf("error fetching and parsing feed: %v", err)
 	}
-
-	if err := a.db.UpdateFeedUpdatedAt(feed.ID); err != nil {
-		return fmt.Errorf("error updating feed timestamp: %v", err)
+	for _, warning := range parsed.Warnings {
+		log.Warn("Feed has problems", "problem", warning)
 	}
-	return nil
-}
-
-// Add these helper functions at the bottom of aggregator.go file:
-
-// Atom feed structs
-type AtomFeed struct {
-	XMLName xml.Name    `xml:"feed"`
-	Entries []AtomEntry `xml:"entry"`
-}
-
-type AtomLink struct {
-	Href string `xml:"href,attr"`
-	Rel  string `xml:"rel,attr"`
-}
 
-type AtomEntry struct {
-	Title     string     `xml:"title"`
-	Link      []AtomLink `xml:"link"`
-	Summary   string     `xml:"summary"`
-	Content   string     `xml:"content"`
-	Published string     `xml:"published"`
-	Updated   string     `xml:"updated"`
+	result, err := a.Ingest(ctx, feed, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("error ingesting feed: %v", err)
+	}
+	return result, nil
 }
 
-func fetchAndParseAtom(url string) (*AtomFeed, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+// Ingest stores a parsed feed: its metadata and parse status are copied to
+// the feed, article bodies are sanitized, the feed's filters drop or rewrite
+// items, the rest are fingerprinted and stored and the ingest hooks are called. It is the pipeline every fetched feed goes
+// through, and can be used to load feeds that were not fetched.
+func (a *Aggregator) Ingest(ctx context.Context, feed domain.Feed, parsed *domain.ParsedFeed) (result *domain.IngestResult, err error) {
+	ctx, span := tracing.Start(ctx, "feed.ingest", "items", len(parsed.Items))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	feed.Title = parsed.Title
+	feed.SiteURL = parsed.Link
+	feed.Description = parsed.Description
+	feed.Language = parsed.Language
+	feed.IconURL = parsed.IconURL
+	feed.LastBuildDate = parsed.LastBuildDate
+	feed.Generator = parsed.Generator
+	feed.TTL = parsed.TTL
+	feed.HubURL = ""
+	if len(parsed.Hubs) > 0 {
+		feed.HubURL = parsed.Hubs[0]
+	}
+	feed.SelfURL = parsed.Self
+	feed.ParseStatus = domain.ParseOK
+	if len(parsed.Warnings) > 0 {
+		feed.ParseStatus = domain.ParseWarnings
 	}
-	defer resp.Body.Close()
+	feed.ParseMessages = append([]string{}, parsed.Warnings...)
 
-	body, err := io.ReadAll(resp.Body)
+	filters, err := a.db.GetFeedFilters(ctx, feed.ID)
 	if err != nil {
 		return nil, err
 	}
-
-	var feed AtomFeed
-	err = xml.Unmarshal(body, &feed)
+	pipeline, err := filter.Compile(filters)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error in filters of feed %s: %v", feed.Name, err)
 	}
-	return &feed, nil
-}
 
-func getAtomLink(links []AtomLink) string {
-	// Try to find the main content link (prefer alternate link)
-	for _, link := range links {
-		if link.Rel == "alternate" && link.Href != "" {
-			return link.Href
+	_, filterSpan := tracing.Start(ctx, "feed.filter", "items", len(parsed.Items), "filters", len(filters))
+	items := parsed.Items[:0]
+	filtered := 0
+	for _, article := range parsed.Items {
+		article.FeedID = feed.ID
+		article.Description = sanitize.HTML(article.Description, article.Link)
+		article.Content = sanitize.HTML(article.Content, article.Link)
+		article.PlainText = plainText(&article)
+		if keep, _ := pipeline.Apply(&article); !keep {
+			filtered++
+			continue
 		}
+		article.ContentHash = contentHash(&article)
+		article.SimHash = simhash.Compute(storyText(&article))
+		items = append(items, article)
 	}
+	filterSpan.Set("kept", len(items), "filtered", filtered)
+	filterSpan.End()
 
-	// Fallback to any link with href
-	for _, link := range links {
-		if link.Href != "" {
-			return link.Href
+	result, err = a.db.IngestArticles(ctx, feed, items)
+	if err != nil {
+		return nil, err
+	}
+	for _, articles := range [][]domain.Article{result.Inserted, result.Updated} {
+		if err := a.db.ClusterArticles(ctx, articles, storyMaxDistance); err != nil {
+			// The articles stay in the stories they were in.
+			a.log.Error("Error clustering articles into stories", "feed", feed.Name, "feed_id", feed.ID, "error", err)
 		}
 	}
+	result.Filtered = filtered
+	articlesInserted.WithLabelValues(feed.Name).Add(float64(len(result.Inserted)))
+	articlesUpdated.Add(float64(len(result.Updated)))
+	articlesFiltered.Add(float64(filtered))
 
-	return ""
+	a.mu.Lock()
+	hooks := a.ingestHooks
+	a.mu.Unlock()
+	_, hookSpan := tracing.Start(ctx, "feed.hooks", "hooks", len(hooks))
+	for _, hook := range hooks {
+		hook(feed, result)
+	}
+	hookSpan.End()
+	return result, nil
 }
 
-func parseAtomDate(dateStr string) (time.Time, error) {
-	if dateStr == "" {
-		return time.Now(), nil
-	}
+// contentHash identifies the editable part of an article so that
+// corrections to the title or body can be told apart from refetches.
+func contentHash(article *domain.Article) string {
+	h := sha256.New()
+	io.WriteString(h, article.Title)
+	h.Write([]byte{0})
+	io.WriteString(h, article.Description)
+	h.Write([]byte{0})
+	io.WriteString(h, article.Content)
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	// Try Atom format (RFC3339) first
-	if t, err := time.Parse(time.RFC3339, dateStr); err == nil {
-		return t, nil
+// plainText is the text of the article body, from the full content when
+// the feed has it and from the description otherwise.
+func plainText(article *domain.Article) string {
+	if article.Content != "" {
+		return sanitize.Text(article.Content)
 	}
+	return sanitize.Text(article.Description)
+}
 
-	// Fallback to RSS format
-	return time.Parse(time.RFC1123, dateStr)
+// storyText is the text fingerprinted to find the same story in other feeds.
+func storyText(article *domain.Article) string {
+	return article.Title + " " + sanitize.Text(article.Description)
 }
@@ -0,0 +1,57 @@
+package aggregator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "rsshub_fetch_duration_seconds",
+		Help: "Time taken to fetch, parse and store a feed, by result.",
+		// Up to the fetch timeout.
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"result"})
+	feedFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rsshub_feed_fetches_total",
+		Help: "Feed fetches, by feed and result.",
+	}, []string{"feed", "result"})
+	articlesInserted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rsshub_articles_inserted_total",
+		Help: "Articles inserted, by feed.",
+	}, []string{"feed"})
+	articlesUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rsshub_articles_updated_total",
+		Help: "Articles updated because their content changed.",
+	})
+	articlesFiltered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rsshub_articles_filtered_total",
+		Help: "Items dropped by ingest filters.",
+	})
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rsshub_queue_depth",
+		Help: "Outdated feeds claimed by the current tick and waiting for a worker.",
+	})
+	workersActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rsshub_workers_active",
+		Help: "Workers processing a feed.",
+	})
+	workersConfigured = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rsshub_workers_configured",
+		Help: "Configured number of workers.",
+	})
+	fetchInterval = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rsshub_fetch_interval_seconds",
+		Help: "Interval between ticks that look for outdated feeds.",
+	})
+	ticks = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rsshub_ticks_total",
+		Help: "Ticks that looked for outdated feeds.",
+	})
+)
+
+// Fetch results.
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
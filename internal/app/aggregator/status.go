@@ -0,0 +1,56 @@
+package aggregator
+
+import (
+	"sort"
+	"time"
+)
+
+// Status is a snapshot of the aggregator, for health checks.
+type Status struct {
+	Running  bool
+	Started  time.Time
+	Interval time.Duration
+	Workers  int
+	// LastTick is when a tick last claimed the outdated feeds and handed
+	// all of them to workers, and LastFetch when a worker last finished
+	// a feed. Both are zero if it has not happened yet.
+	LastTick  time.Time
+	LastFetch time.Time
+	// Busy are the workers processing a feed, by worker number.
+	Busy []WorkerStatus
+}
+
+// WorkerStatus is the feed a worker is processing and since when.
+type WorkerStatus struct {
+	Worker int
+	Feed   string
+	URL    string
+	Since  time.Time
+}
+
+// Status returns a snapshot of the aggregator.
+func (a *Aggregator) Status() Status {
+	a.statusMu.Lock()
+	defer a.statusMu.Unlock()
+	s := Status{
+		Running:   !a.started.IsZero(),
+		Started:   a.started,
+		Interval:  a.statusInterval,
+		Workers:   a.statusWorkers,
+		LastTick:  a.lastTick,
+		LastFetch: a.lastFetch,
+	}
+	for _, w := range a.busy {
+		s.Busy = append(s.Busy, w)
+	}
+	sort.Slice(s.Busy, func(i, j int) bool { return s.Busy[i].Worker < s.Busy[j].Worker })
+	return s
+}
+
+// setStatus records changes to the aggregator's settings. The status has
+// its own lock so that health checks never wait for a.mu.
+func (a *Aggregator) setStatus(fn func()) {
+	a.statusMu.Lock()
+	defer a.statusMu.Unlock()
+	fn()
+}
@@ -0,0 +1,325 @@
+// Package digest builds email digests of new articles and sends them on
+// their daily or weekly schedule.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"rsshub/internal/adapters/db"
+	"rsshub/internal/domain"
+	"rsshub/pkg/logger"
+	"rsshub/pkg/mailer"
+)
+
+const (
+	// checkInterval is how often the scheduler looks for digests that are due.
+	checkInterval = time.Minute
+	// maxArticles is the largest number of articles in one digest. The
+	// rest are sent in the next one.
+	maxArticles = 500
+	// snippetLength is the length, in characters, of article excerpts.
+	snippetLength = 240
+	// untagged is the group of articles of feeds without tags.
+	untagged = "untagged"
+)
+
+// Period returns the time between two digests on a schedule.
+func Period(schedule string) time.Duration {
+	if schedule == domain.DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// ParseTime parses a time of day given as 15:04.
+func ParseTime(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// Next returns the first time after after that a digest is scheduled for,
+// in the time zone of after.
+func Next(d domain.Digest, after time.Time) time.Time {
+	hour, minute, err := ParseTime(d.SendTime)
+	if err != nil {
+		hour, minute = 8, 0
+	}
+	next := time.Date(after.Year(), after.Month(), after.Day(), hour, minute, 0, 0, after.Location())
+	if d.Schedule == domain.DigestWeekly {
+		next = next.AddDate(0, 0, (int(d.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(after) {
+		next = next.AddDate(0, 0, int(Period(d.Schedule)/(24*time.Hour)))
+	}
+	return next
+}
+
+// Due reports whether a digest should be sent at now: whether a scheduled
+// time has passed since it was last sent, or since it was added.
+func Due(d domain.Digest, now time.Time) bool {
+	last := d.LastSentAt
+	if last.IsZero() {
+		last = d.CreatedAt
+	}
+	return !Next(d, last.In(now.Location())).After(now)
+}
+
+// Group is the articles of a digest from one feed or with one tag.
+type Group struct {
+	Name     string
+	Articles []Entry
+}
+
+// Entry is an article as shown in a digest.
+type Entry struct {
+	Title     string
+	Link      string
+	Feed      string
+	Published string
+	Snippet   string
+}
+
+// Build returns the message of a digest with the given articles, grouped by
+// feed or by tag. Articles of feeds with several tags appear under each.
+func Build(d domain.Digest, articles []domain.DigestArticle, now time.Time) mailer.Message {
+	groups := make(map[string]*Group)
+	var names []string
+	add := func(name string, e Entry) {
+		g, ok := groups[name]
+		if !ok {
+			g = &Group{Name: name}
+			groups[name] = g
+			names = append(names, name)
+		}
+		g.Articles = append(g.Articles, e)
+	}
+	for _, a := range articles {
+		e := entry(a)
+		switch {
+		case d.GroupBy != domain.DigestByTag:
+			name := a.FeedTitle
+			if name == "" {
+				name = a.FeedName
+			}
+			add(name, e)
+		case len(a.FeedTags) == 0:
+			add(untagged, e)
+		default:
+			for _, tag := range a.FeedTags {
+				add(tag, e)
+			}
+		}
+	}
+	sort.Strings(names)
+	data := struct {
+		Name     string
+		Date     string
+		Count    int
+		Groups   []*Group
+		Schedule string
+	}{Name: d.Name, Date: now.Format("Monday, 2 January 2006"), Count: len(articles), Schedule: d.Schedule}
+	for _, name := range names {
+		data.Groups = append(data.Groups, groups[name])
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		text.WriteString(err.Error())
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		html.Reset()
+	}
+	return mailer.Message{
+		To:      d.Recipients,
+		Subject: fmt.Sprintf("[%s] %d new articles, %s", d.Name, len(articles), now.Format("Mon 2 Jan")),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}
+}
+
+func entry(a domain.DigestArticle) Entry {
+	e := Entry{Title: a.Title, Link: a.Link, Feed: a.FeedTitle, Snippet: snippet(a.PlainText)}
+	if e.Title == "" {
+		e.Title = a.Link
+	}
+	if e.Feed == "" {
+		e.Feed = a.FeedName
+	}
+	if !a.PublishedAt.IsZero() {
+		e.Published = a.PublishedAt.Format("2006-01-02 15:04")
+	}
+	return e
+}
+
+// snippet returns the start of text, cut at a word boundary.
+func snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= snippetLength {
+		return text
+	}
+	cut := string(runes[:snippetLength])
+	if i := strings.LastIndexByte(cut, ' '); i > snippetLength/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`{{.Name}}: {{.Count}} new articles
+{{.Date}}
+{{range .Groups}}
+== {{.Name}} ==
+{{range .Articles}}
+* {{.Title}}
+  {{.Link}}
+  {{.Feed}}{{if .Published}}, {{.Published}}{{end}}
+{{- if .Snippet}}
+  {{.Snippet}}
+{{- end}}
+{{end}}{{end}}
+You receive this {{.Schedule}} digest from rsshub.
+`))
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 640px; margin: 0 auto; color: #222;">
+<h1 style="font-size: 20px;">{{.Name}}: {{.Count}} new articles</h1>
+<p style="color: #666;">{{.Date}}</p>
+{{range .Groups}}
+<h2 style="font-size: 16px; border-bottom: 1px solid #ddd; padding-bottom: 4px;">{{.Name}}</h2>
+<ul style="padding-left: 0; list-style: none;">
+{{range .Articles}}<li style="margin-bottom: 12px;">
+<a href="{{.Link}}" style="font-weight: bold;">{{.Title}}</a><br>
+<small style="color: #666;">{{.Feed}}{{if .Published}}, {{.Published}}{{end}}</small>
+{{if .Snippet}}<div style="margin-top: 4px;">{{.Snippet}}</div>{{end}}
+</li>
+{{end}}</ul>
+{{end}}
+<p style="color: #999; font-size: 12px;">You receive this {{.Schedule}} digest from rsshub.</p>
+</body>
+</html>
+`))
+
+// Sender builds digests and sends them, on their schedule with Start or on
+// demand with Send.
+type Sender struct {
+	db     *db.DB
+	mailer *mailer.Mailer
+	log    *slog.Logger
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewSender(db *db.DB, mailer *mailer.Mailer) *Sender {
+	return &Sender{db: db, mailer: mailer, log: logger.With("component", "digests")}
+}
+
+// Send sends a digest of the articles added since the previous one and
+// returns their number. Nothing is sent if there are none, but the digest
+// is still marked as sent so that it waits for its next scheduled time.
+func (s *Sender) Send(ctx context.Context, d domain.Digest) (int, error) {
+	now := time.Now()
+	articles, err := s.db.GetDigestArticles(ctx, d, Period(d.Schedule), maxArticles)
+	if err != nil {
+		return 0, err
+	}
+	var last *domain.Article
+	if len(articles) > 0 {
+		if err := s.mailer.Send(Build(d, articles, now)); err != nil {
+			return 0, err
+		}
+		last = &articles[len(articles)-1].Article
+	}
+	if err := s.db.MarkDigestSent(ctx, d.ID, now, last); err != nil {
+		return len(articles), err
+	}
+	return len(articles), nil
+}
+
+// Preview returns the message that Send would send, or nil if there are no
+// new articles, without sending it.
+func (s *Sender) Preview(ctx context.Context, d domain.Digest) (*mailer.Message, error) {
+	articles, err := s.db.GetDigestArticles(ctx, d, Period(d.Schedule), maxArticles)
+	if err != nil || len(articles) == 0 {
+		return nil, err
+	}
+	msg := Build(d, articles, time.Now())
+	return &msg, nil
+}
+
+func (s *Sender) Start(parentCtx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx != nil {
+		return fmt.Errorf("already started")
+	}
+	s.ctx, s.cancel = context.WithCancel(parentCtx)
+	s.wg.Add(1)
+	go s.loop()
+	return nil
+}
+
+func (s *Sender) Stop() error {
+	s.mu.Lock()
+	if s.ctx == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("not started")
+	}
+	s.cancel()
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.mu.Lock()
+	s.ctx = nil
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Sender) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.sendDue()
+		}
+	}
+}
+
+// sendDue sends the digests that are due.
+func (s *Sender) sendDue() {
+	digests, err := s.db.ListDigests(s.ctx)
+	if err != nil {
+		s.log.Error("Error getting digests", "error", err)
+		return
+	}
+	now := time.Now()
+	for _, d := range digests {
+		if !Due(d, now) {
+			continue
+		}
+		n, err := s.Send(s.ctx, d)
+		if err != nil {
+			s.log.Error("Error sending digest", "digest", d.Name, "error", err)
+			continue
+		}
+		s.log.Info("Digest sent", "digest", d.Name, "articles", n, "to", strings.Join(d.Recipients, ", "))
+	}
+}
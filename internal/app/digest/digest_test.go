@@ -0,0 +1,87 @@
+package digest
+
+import (
+	"testing"
+	"time"
+
+	"rsshub/internal/domain"
+)
+
+// date returns a time on March 2025, in which the 3rd is a Monday.
+func date(day, hour, minute int) time.Time {
+	return time.Date(2025, time.March, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	daily := domain.Digest{Schedule: domain.DigestDaily, SendTime: "08:00"}
+	weekly := domain.Digest{Schedule: domain.DigestWeekly, SendTime: "18:30", Weekday: time.Friday}
+	tests := []struct {
+		name   string
+		digest domain.Digest
+		after  time.Time
+		want   time.Time
+	}{
+		{"daily before time", daily, date(3, 7, 59), date(3, 8, 0)},
+		{"daily at time", daily, date(3, 8, 0), date(4, 8, 0)},
+		{"daily after time", daily, date(3, 12, 0), date(4, 8, 0)},
+		{"daily across month", daily, date(31, 9, 0), time.Date(2025, time.April, 1, 8, 0, 0, 0, time.UTC)},
+		{"daily invalid time", domain.Digest{Schedule: domain.DigestDaily, SendTime: "8am"}, date(3, 9, 0), date(4, 8, 0)},
+		{"weekly earlier in week", weekly, date(3, 9, 0), date(7, 18, 30)},
+		{"weekly same day before", weekly, date(7, 18, 0), date(7, 18, 30)},
+		{"weekly same day at", weekly, date(7, 18, 30), date(14, 18, 30)},
+		{"weekly later in week", weekly, date(8, 9, 0), date(14, 18, 30)},
+		{"weekly on sunday", domain.Digest{Schedule: domain.DigestWeekly, SendTime: "07:00", Weekday: time.Sunday},
+			date(3, 9, 0), date(9, 7, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Next(tt.digest, tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTimeZone(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	d := domain.Digest{Schedule: domain.DigestDaily, SendTime: "08:00"}
+	after := time.Date(2025, time.March, 3, 7, 0, 0, 0, zone)
+	want := time.Date(2025, time.March, 3, 8, 0, 0, 0, zone)
+	if got := Next(d, after); !got.Equal(want) {
+		t.Errorf("Next(%v) = %v, want %v", after, got, want)
+	}
+}
+
+func TestDue(t *testing.T) {
+	daily := domain.Digest{Schedule: domain.DigestDaily, SendTime: "08:00", CreatedAt: date(2, 12, 0)}
+	weekly := domain.Digest{Schedule: domain.DigestWeekly, SendTime: "08:00", Weekday: time.Monday,
+		CreatedAt: date(1, 12, 0)}
+	sent := func(d domain.Digest, at time.Time) domain.Digest {
+		d.LastSentAt = at
+		return d
+	}
+	tests := []struct {
+		name   string
+		digest domain.Digest
+		now    time.Time
+		want   bool
+	}{
+		{"never sent, before first time", daily, date(3, 7, 59), false},
+		{"never sent, at first time", daily, date(3, 8, 0), true},
+		{"never sent, long after", daily, date(20, 8, 0), true},
+		{"sent today, before next", sent(daily, date(3, 8, 1)), date(4, 7, 0), false},
+		{"sent today, at next", sent(daily, date(3, 8, 1)), date(4, 8, 0), true},
+		{"sent late, next not yet", sent(daily, date(3, 23, 0)), date(4, 7, 59), false},
+		{"weekly, midweek", sent(weekly, date(3, 8, 0)), date(7, 8, 0), false},
+		{"weekly, next monday", sent(weekly, date(3, 8, 0)), date(10, 8, 0), true},
+		{"weekly, never sent", weekly, date(3, 8, 0), true},
+		{"weekly, never sent, before first monday", weekly, date(2, 9, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Due(tt.digest, tt.now); got != tt.want {
+				t.Errorf("Due(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"rsshub/internal/adapters/db"
+	"rsshub/internal/domain"
+	"rsshub/pkg/charset"
+	"rsshub/pkg/logger"
+	"rsshub/pkg/readability"
+	"rsshub/pkg/sanitize"
+)
+
+const (
+	// maxAttempts is how often a page is tried before it is given up on.
+	maxAttempts = 3
+	// maxPageSize caps the bytes read from an article page.
+	maxPageSize = 5 << 20
+)
+
+// Extractor downloads the pages of articles from feeds in full-text mode and
+// stores their main content. It runs its own worker pool and rate limits,
+// separate from the aggregator, so slow article sites never delay polling.
+type Extractor struct {
+	db         *db.DB
+	client     *http.Client
+	limiter    *limiter
+	interval   time.Duration
+	numWorkers int
+	jobs       chan domain.Article
+	wake       chan struct{}
+	mu         sync.Mutex
+	inFlight   map[string]bool
+	running    bool
+	wg         sync.WaitGroup
+	cancel     context.CancelFunc
+	log        *slog.Logger
+}
+
+// NewExtractor creates an extractor that looks for pending articles every
+// interval (and whenever Notify is called), with at most one request every
+// gap overall and one every hostDelay to the same site.
+func NewExtractor(db *db.DB, interval time.Duration, numWorkers int, gap, hostDelay time.Duration) *Extractor {
+	return &Extractor{
+		db:         db,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		limiter:    newLimiter(gap, hostDelay),
+		interval:   interval,
+		numWorkers: numWorkers,
+		jobs:       make(chan domain.Article),
+		wake:       make(chan struct{}, 1),
+		inFlight:   make(map[string]bool),
+		log:        logger.With("component", "extractor"),
+	}
+}
+
+func (e *Extractor) Start(parentCtx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running {
+		return fmt.Errorf("already started")
+	}
+	e.running = true
+	ctx, cancel := context.WithCancel(parentCtx)
+	e.cancel = cancel
+	e.wg.Add(1)
+	go e.dispatchLoop(ctx)
+	for i := 0; i < e.numWorkers; i++ {
+		e.wg.Add(1)
+		go e.worker(ctx)
+	}
+	return nil
+}
+
+func (e *Extractor) Stop() error {
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return fmt.Errorf("not started")
+	}
+	e.running = false
+	e.cancel()
+	e.mu.Unlock()
+	e.wg.Wait()
+	return nil
+}
+
+// Notify wakes the extractor to look for new articles now instead of at
+// its next interval. It never blocks.
+func (e *Extractor) Notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *Extractor) dispatchLoop(ctx context.Context) {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.wake:
+		}
+	}
+}
+
+// dispatch hands pending articles to the workers, skipping those that are
+// still being worked on.
+func (e *Extractor) dispatch(ctx context.Context) {
+	e.mu.Lock()
+	exclude := make([]string, 0, len(e.inFlight))
+	for id := range e.inFlight {
+		exclude = append(exclude, id)
+	}
+	e.mu.Unlock()
+
+	articles, err := e.db.GetPendingExtractions(ctx, e.numWorkers*4, maxAttempts, exclude)
+	if err != nil {
+		e.log.Error("Error fetching articles to extract", "error", err)
+		return
+	}
+	for _, article := range articles {
+		e.mu.Lock()
+		e.inFlight[article.ID] = true
+		e.mu.Unlock()
+		select {
+		case e.jobs <- article:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Extractor) worker(ctx context.Context) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case article := <-e.jobs:
+			start := time.Now()
+			log := e.log.With("article_id", article.ID, "feed_id", article.FeedID, "url", article.Link)
+			if err := e.process(ctx, article); err != nil && ctx.Err() == nil {
+				log.Warn("Error extracting article", "duration", time.Since(start), "error", err)
+				if err := e.db.FailExtraction(ctx, article.ID, err.Error()); err != nil {
+					log.Error("Error recording failed extraction", "error", err)
+				}
+			} else if err == nil {
+				log.Debug("Article extracted", "duration", time.Since(start))
+			}
+			e.mu.Lock()
+			delete(e.inFlight, article.ID)
+			e.mu.Unlock()
+		}
+	}
+}
+
+func (e *Extractor) process(ctx context.Context, article domain.Article) error {
+	u, err := url.Parse(article.Link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid link")
+	}
+	if err := e.limiter.Wait(ctx, u.Host); err != nil {
+		return err
+	}
+
+	page, finalURL, err := e.fetch(ctx, article.Link)
+	if err != nil {
+		return err
+	}
+	content, err := readability.Extract(page, finalURL)
+	if err != nil {
+		return err
+	}
+	return e.db.SaveExtraction(ctx, article.ID, content, sanitize.Text(content))
+}
+
+// fetch downloads an HTML page and returns it, converted to UTF-8, with the URL it was served
+// from after redirects.
+func (e *Extractor) fetch(ctx context.Context, link string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("User-Agent", "rsshub (full-text extraction)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return "", "", fmt.Errorf("unexpected content type %s", ct)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return "", "", err
+	}
+	body, err = charset.ToUTF8(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", "", err
+	}
+	return string(body), resp.Request.URL.String(), nil
+}
@@ -0,0 +1,54 @@
+package extractor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter spaces out requests: at most one per gap overall and one per
+// hostDelay to each host. Callers reserve a slot and then wait for it, so
+// concurrent workers never burst.
+type limiter struct {
+	mu        sync.Mutex
+	gap       time.Duration
+	hostDelay time.Duration
+	next      time.Time
+	hosts     map[string]time.Time
+}
+
+func newLimiter(gap, hostDelay time.Duration) *limiter {
+	return &limiter{gap: gap, hostDelay: hostDelay, hosts: make(map[string]time.Time)}
+}
+
+// Wait blocks until a request to host may be made, or ctx is done.
+func (l *limiter) Wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.next.After(at) {
+		at = l.next
+	}
+	if next, ok := l.hosts[host]; ok && next.After(at) {
+		at = next
+	}
+	l.next = at.Add(l.gap)
+	l.hosts[host] = at.Add(l.hostDelay)
+	if len(l.hosts) > 1000 {
+		for h, next := range l.hosts {
+			if next.Before(now) {
+				delete(l.hosts, h)
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,136 @@
+// Package filter runs the ingest pipelines that drop or rewrite the items
+// of a feed before they are stored.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"rsshub/internal/domain"
+	"rsshub/pkg/urlnorm"
+)
+
+// Pipeline is a compiled list of filters.
+type Pipeline struct {
+	steps []step
+}
+
+type step struct {
+	filter domain.Filter
+	re     *regexp.Regexp
+}
+
+// Compile checks and compiles the filters of a feed. Patterns match case-
+// insensitively.
+func Compile(filters []domain.Filter) (*Pipeline, error) {
+	p := &Pipeline{}
+	for _, f := range filters {
+		s := step{filter: f}
+		switch f.Action {
+		case domain.FilterInclude, domain.FilterExclude:
+			if f.Field != domain.FilterTitle && f.Field != domain.FilterCategory {
+				return nil, fmt.Errorf("%s filter: unknown field %q", f.Action, f.Field)
+			}
+		case domain.FilterRewriteTitle, domain.FilterStripParams:
+		case domain.FilterMinLength:
+			if f.MinLength <= 0 {
+				return nil, fmt.Errorf("min-length filter: length must be positive")
+			}
+			p.steps = append(p.steps, s)
+			continue
+		default:
+			return nil, fmt.Errorf("unknown filter %q", f.Action)
+		}
+		if f.Pattern == "" {
+			return nil, fmt.Errorf("%s filter: missing pattern", f.Action)
+		}
+		re, err := regexp.Compile("(?i)" + f.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s filter: %v", f.Action, err)
+		}
+		s.re = re
+		p.steps = append(p.steps, s)
+	}
+	return p, nil
+}
+
+// Apply runs an item through the pipeline, rewriting it in place. It
+// returns false, with the filter that dropped it, if the item must not be
+// stored. Items must have their plain text set.
+func (p *Pipeline) Apply(article *domain.Article) (bool, *domain.Filter) {
+	for i := range p.steps {
+		s := &p.steps[i]
+		switch s.filter.Action {
+		case domain.FilterInclude:
+			if !s.match(article) {
+				return false, &s.filter
+			}
+		case domain.FilterExclude:
+			if s.match(article) {
+				return false, &s.filter
+			}
+		case domain.FilterMinLength:
+			if utf8.RuneCountInString(article.PlainText) < s.filter.MinLength {
+				return false, &s.filter
+			}
+		case domain.FilterRewriteTitle:
+			article.Title = s.re.ReplaceAllString(article.Title, s.filter.Replacement)
+		case domain.FilterStripParams:
+			article.Link = stripParams(article.Link, s.re)
+		}
+	}
+	return true, nil
+}
+
+// Len returns the number of filters in the pipeline.
+func (p *Pipeline) Len() int {
+	return len(p.steps)
+}
+
+func (s *step) match(article *domain.Article) bool {
+	if s.filter.Field == domain.FilterCategory {
+		for _, c := range article.Categories {
+			if s.re.MatchString(c) {
+				return true
+			}
+		}
+		return false
+	}
+	return s.re.MatchString(article.Title)
+}
+
+// stripParams removes the query parameters whose names match re from link,
+// leaving the rest of it as it was.
+func stripParams(link string, re *regexp.Regexp) string {
+	rest, fragment, hasFragment := strings.Cut(link, "#")
+	base, rawQuery, ok := strings.Cut(rest, "?")
+	if !ok {
+		return link
+	}
+	if rawQuery = urlnorm.StripParams(rawQuery, re.MatchString); rawQuery != "" {
+		base += "?" + rawQuery
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
+
+// Describe returns a one-line description of a filter.
+func Describe(f domain.Filter) string {
+	switch f.Action {
+	case domain.FilterInclude:
+		return fmt.Sprintf("keep only items whose %s matches /%s/", f.Field, f.Pattern)
+	case domain.FilterExclude:
+		return fmt.Sprintf("drop items whose %s matches /%s/", f.Field, f.Pattern)
+	case domain.FilterMinLength:
+		return fmt.Sprintf("drop items shorter than %d characters", f.MinLength)
+	case domain.FilterRewriteTitle:
+		return fmt.Sprintf("replace /%s/ in titles with %q", f.Pattern, f.Replacement)
+	case domain.FilterStripParams:
+		return fmt.Sprintf("remove link parameters matching /%s/", f.Pattern)
+	}
+	return f.Action
+}
@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"regexp"
+	"testing"
+
+	"rsshub/internal/domain"
+)
+
+func TestStripParams(t *testing.T) {
+	re := regexp.MustCompile("(?i)^(ref|source)$")
+	tests := []struct {
+		link, want string
+	}{
+		{"https://example.com/a", "https://example.com/a"},
+		{"https://example.com/a?ref=rss", "https://example.com/a"},
+		{"https://example.com/a?REF=rss&id=1", "https://example.com/a?id=1"},
+
+		// The other parameters keep their order and encoding.
+		{"https://example.com/a?z=1&ref=rss&a=2", "https://example.com/a?z=1&a=2"},
+		{"https://example.com/a?q=a+b%2Fc&source=x&flag", "https://example.com/a?q=a+b%2Fc&flag"},
+		{"https://example.com/a?r%65f=x&b=%E2%9C%93", "https://example.com/a?b=%E2%9C%93"},
+
+		// Fragments stay, and question marks in them are not a query.
+		{"https://example.com/a?ref=x#top", "https://example.com/a#top"},
+		{"https://example.com/a#?ref=x", "https://example.com/a#?ref=x"},
+		{"https://example.com/a?id=1#s?ref=x", "https://example.com/a?id=1#s?ref=x"},
+	}
+	for _, tt := range tests {
+		if got := stripParams(tt.link, re); got != tt.want {
+			t.Errorf("stripParams(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		filter domain.Filter
+		ok     bool
+	}{
+		{domain.Filter{Action: domain.FilterInclude, Field: domain.FilterTitle, Pattern: "go"}, true},
+		{domain.Filter{Action: domain.FilterExclude, Field: domain.FilterCategory, Pattern: "sponsored"}, true},
+		{domain.Filter{Action: domain.FilterExclude, Field: "author", Pattern: "bot"}, false},
+		{domain.Filter{Action: domain.FilterInclude, Field: domain.FilterTitle}, false},
+		{domain.Filter{Action: domain.FilterInclude, Field: domain.FilterTitle, Pattern: "("}, false},
+		{domain.Filter{Action: domain.FilterMinLength, MinLength: 100}, true},
+		{domain.Filter{Action: domain.FilterMinLength}, false},
+		{domain.Filter{Action: domain.FilterRewriteTitle, Pattern: `^\[ad\] `}, true},
+		{domain.Filter{Action: domain.FilterStripParams, Pattern: "^ref$"}, true},
+		{domain.Filter{Action: domain.FilterStripParams}, false},
+		{domain.Filter{Action: "drop"}, false},
+	}
+	for _, tt := range tests {
+		if _, err := Compile([]domain.Filter{tt.filter}); (err == nil) != tt.ok {
+			t.Errorf("Compile(%s) error = %v, want ok %v", Describe(tt.filter), err, tt.ok)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	p, err := Compile([]domain.Filter{
+		{Action: domain.FilterExclude, Field: domain.FilterCategory, Pattern: "^sponsored$"},
+		{Action: domain.FilterRewriteTitle, Pattern: `^\[(news|update)\]\s*`, Replacement: ""},
+		{Action: domain.FilterInclude, Field: domain.FilterTitle, Pattern: `\bgo\b`},
+		{Action: domain.FilterMinLength, MinLength: 10},
+		{Action: domain.FilterStripParams, Pattern: "^ref$"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 5 {
+		t.Errorf("Len() = %d, want 5", p.Len())
+	}
+	tests := []struct {
+		name    string
+		article domain.Article
+		keep    bool
+		dropped string // action of the filter that drops it
+		title   string
+		link    string
+	}{
+		{"kept and rewritten", domain.Article{Title: "[NEWS] Go 1.24 is out", Link: "https://go.dev/?ref=rss&v=1",
+			PlainText: "Go 1.24 is out today."}, true, "", "Go 1.24 is out", "https://go.dev/?v=1"},
+		{"sponsored", domain.Article{Title: "Go hosting", Categories: []string{"Tech", "SPONSORED"},
+			PlainText: "Buy our hosting now."}, false, domain.FilterExclude, "", ""},
+		{"off topic", domain.Article{Title: "[update] Rust news", PlainText: "Rust 1.80 is out today."},
+			false, domain.FilterInclude, "", ""},
+		{"include matches rewritten title", domain.Article{Title: "[news] go", PlainText: "Short"},
+			false, domain.FilterMinLength, "", ""},
+		{"length in characters", domain.Article{Title: "Go", Link: "https://go.dev/", PlainText: "ééééééééééé"},
+			true, "", "Go", "https://go.dev/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := tt.article
+			keep, f := p.Apply(&article)
+			if keep != tt.keep {
+				t.Fatalf("Apply() = %v, want %v", keep, tt.keep)
+			}
+			if !keep {
+				if f == nil || f.Action != tt.dropped {
+					t.Errorf("dropped by %v, want %s", f, tt.dropped)
+				}
+				return
+			}
+			if f != nil || article.Title != tt.title || article.Link != tt.link {
+				t.Errorf("Apply() = %v, %q, %q, want %q, %q", f, article.Title, article.Link, tt.title, tt.link)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Package health checks that the fetch process is alive and able to work:
+// that the database answers, that the aggregator keeps ticking and that its
+// workers are not stuck on a feed.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"rsshub/internal/adapters/db"
+	"rsshub/internal/app/aggregator"
+)
+
+const (
+	// pingTimeout bounds how long the database may take to answer.
+	pingTimeout = 2 * time.Second
+	// tickGrace is how late a tick may be, on top of two intervals, before
+	// the aggregator is considered wedged. Ticks are late while all workers
+	// are busy.
+	tickGrace = time.Minute
+	// stuckAfter is how long a worker may spend on one feed.
+	stuckAfter = 10 * time.Minute
+)
+
+// Names of the checks.
+const (
+	CheckDatabase   = "database"
+	CheckAggregator = "aggregator"
+	CheckTicks      = "ticks"
+	CheckWorkers    = "workers"
+)
+
+// Statuses of a report.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check is the result of one check.
+type Check struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// Report is the result of a set of checks, with the state of the
+// aggregator they were made on.
+type Report struct {
+	Status    string     `json:"status"`
+	Time      time.Time  `json:"time"`
+	Checks    []Check    `json:"checks"`
+	LastTick  *time.Time `json:"last_tick,omitempty"`
+	LastFetch *time.Time `json:"last_fetch,omitempty"`
+	Interval  string     `json:"interval,omitempty"`
+	Workers   int        `json:"workers"`
+	Busy      int        `json:"busy"`
+}
+
+// OK reports whether all checks passed.
+func (r *Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Checker checks the database and aggregator of a fetch process.
+type Checker struct {
+	db  *db.DB
+	agg *aggregator.Aggregator
+}
+
+func NewChecker(db *db.DB, agg *aggregator.Aggregator) *Checker {
+	return &Checker{db: db, agg: agg}
+}
+
+// Live checks that the aggregator is not wedged: that it ticks and that
+// its workers make progress. A process that fails it should be restarted.
+func (c *Checker) Live(ctx context.Context) *Report {
+	return c.report(ctx, CheckTicks, CheckWorkers)
+}
+
+// Ready checks that the process can do work: that the aggregator is
+// running and the database answers.
+func (c *Checker) Ready(ctx context.Context) *Report {
+	return c.report(ctx, CheckDatabase, CheckAggregator)
+}
+
+// All makes every check.
+func (c *Checker) All(ctx context.Context) *Report {
+	return c.report(ctx, CheckDatabase, CheckAggregator, CheckTicks, CheckWorkers)
+}
+
+func (c *Checker) report(ctx context.Context, names ...string) *Report {
+	now := time.Now()
+	status := c.agg.Status()
+	r := &Report{Status: StatusOK, Time: now.UTC(), Workers: status.Workers, Busy: len(status.Busy)}
+	if status.Running {
+		r.Interval = status.Interval.String()
+	}
+	if !status.LastTick.IsZero() {
+		t := status.LastTick.UTC()
+		r.LastTick = &t
+	}
+	if !status.LastFetch.IsZero() {
+		t := status.LastFetch.UTC()
+		r.LastFetch = &t
+	}
+	for _, name := range names {
+		var check Check
+		switch name {
+		case CheckDatabase:
+			check = c.checkDatabase(ctx)
+		case CheckAggregator:
+			check = checkAggregator(status)
+		case CheckTicks:
+			check = checkTicks(status, now)
+		case CheckWorkers:
+			check = checkWorkers(status, now)
+		}
+		check.Name = name
+		if !check.OK {
+			r.Status = StatusFail
+		}
+		r.Checks = append(r.Checks, check)
+	}
+	return r
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) Check {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := c.db.PingContext(ctx); err != nil {
+		return Check{Message: fmt.Sprintf("unreachable: %v", err)}
+	}
+	return Check{OK: true, Message: fmt.Sprintf("reachable in %s", round(time.Since(start)))}
+}
+
+func checkAggregator(s aggregator.Status) Check {
+	if !s.Running {
+		return Check{Message: "not running"}
+	}
+	return Check{OK: true, Message: fmt.Sprintf("running since %s", s.Started.UTC().Format(time.RFC3339))}
+}
+
+// checkTicks fails if the aggregator has not completed a tick for more
+// than two intervals. It counts from the start until the first tick.
+func checkTicks(s aggregator.Status, now time.Time) Check {
+	if !s.Running {
+		return Check{Message: "aggregator not running"}
+	}
+	last, what := s.LastTick, "last tick"
+	if last.IsZero() {
+		last, what = s.Started, "no tick since start"
+	}
+	age := now.Sub(last)
+	if age > 2*s.Interval+tickGrace {
+		return Check{Message: fmt.Sprintf("%s %s ago, interval %s", what, round(age), s.Interval)}
+	}
+	return Check{OK: true, Message: fmt.Sprintf("%s %s ago", what, round(age))}
+}
+
+// checkWorkers fails if a worker has spent more than stuckAfter on one
+// feed.
+func checkWorkers(s aggregator.Status, now time.Time) Check {
+	if !s.Running {
+		return Check{Message: "aggregator not running"}
+	}
+	for _, w := range s.Busy {
+		if d := now.Sub(w.Since); d > stuckAfter {
+			return Check{Message: fmt.Sprintf("worker %d stuck on feed %s for %s", w.Worker, w.Feed, round(d))}
+		}
+	}
+	msg := fmt.Sprintf("%d of %d busy", len(s.Busy), s.Workers)
+	if !s.LastFetch.IsZero() {
+		msg += fmt.Sprintf(", last fetch %s ago", round(now.Sub(s.LastFetch)))
+	}
+	return Check{OK: true, Message: msg}
+}
+
+func round(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+// Handler serves the report of check as JSON, with status 200 if it
+// passed and 503 if it failed.
+func Handler(check func(context.Context) *Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := check(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !report.OK() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,323 @@
+package route
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/charset"
+	"rsshub/pkg/urlnorm"
+)
+
+// Scheme is the URL scheme of virtual feeds generated by routes, as in
+// route://example-news.
+const Scheme = "route"
+
+// maxPages caps paging regardless of what a rule asks for.
+const maxPages = 20
+
+const (
+	// fetchTimeout bounds the request for one page, body included.
+	fetchTimeout = 30 * time.Second
+	// maxPageSize caps the bytes read from a page.
+	maxPageSize = 5 << 20
+)
+
+var client = &http.Client{Timeout: fetchTimeout}
+
+var (
+	namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	unixPattern = regexp.MustCompile(`^[0-9]{9,10}$`)
+)
+
+// Rule describes how to turn an HTML page or a JSON API into a feed. Field
+// values in Fields are CSS selectors for HTML, optionally followed by
+// "@attr" to read an attribute or "@html" to keep the inner HTML, and
+// dotted paths such as "data.posts" or "links[0].href" for JSON.
+type Rule struct {
+	Name        string            `json:"-"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	URL         string            `json:"url"`  // may contain {page}
+	Type        string            `json:"type"` // "html" or "json"
+	Items       string            `json:"items"`
+	Fields      map[string]string `json:"fields"`      // title, link, date, description, content, author, guid
+	DateFormat  string            `json:"date_format"` // Go layout, or "unix"
+	Paging      Paging            `json:"paging"`
+}
+
+// Paging follows a "next page" link, or substitutes {page} in the URL from
+// Start on, for up to MaxPages pages.
+type Paging struct {
+	Next     string `json:"next"`
+	Start    int    `json:"start"`
+	MaxPages int    `json:"max_pages"`
+}
+
+// Load reads the rule name from dir, where it is stored as name.json.
+func Load(dir, name string) (*Rule, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid route name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var rule Rule
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return nil, fmt.Errorf("invalid route %s: %v", name, err)
+	}
+	rule.Name = name
+	if err := rule.validate(); err != nil {
+		return nil, fmt.Errorf("invalid route %s: %v", name, err)
+	}
+	return &rule, nil
+}
+
+// List returns the names of the rules in dir.
+func List(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, p := range paths {
+		name := strings.TrimSuffix(filepath.Base(p), ".json")
+		if namePattern.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Name returns the route name of a route:// URL, or "" for other URLs.
+func Name(feedURL string) string {
+	name, ok := strings.CutPrefix(feedURL, Scheme+"://")
+	if !ok {
+		return ""
+	}
+	return strings.Trim(name, "/")
+}
+
+func (r *Rule) validate() error {
+	if r.URL == "" {
+		return fmt.Errorf("missing url")
+	}
+	if r.Type == "" {
+		r.Type = "html"
+	}
+	if r.Type != "html" && r.Type != "json" {
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+	if r.Items == "" && r.Type == "html" {
+		return fmt.Errorf("missing items selector")
+	}
+	if r.Fields["title"] == "" && r.Fields["link"] == "" {
+		return fmt.Errorf("fields need at least a title or a link")
+	}
+	if r.Type == "html" {
+		for key, field := range r.Fields {
+			if _, _, err := compileField(field); err != nil {
+				return fmt.Errorf("field %s: %v", key, err)
+			}
+		}
+		if r.Paging.Next != "" {
+			if _, _, err := compileField(r.Paging.Next); err != nil {
+				return fmt.Errorf("paging: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// item is one scraped entry, before it is turned into an article.
+type item map[string]string
+
+// Fetch downloads the pages of the rule and returns them as a feed.
+func (r *Rule) Fetch(ctx context.Context) (*models.ParsedFeed, error) {
+	pages := max(1, min(r.Paging.MaxPages, maxPages))
+	page := r.Paging.Start
+	if page == 0 && strings.Contains(r.URL, "{page}") {
+		page = 1
+	}
+	pageURL := strings.ReplaceAll(r.URL, "{page}", fmt.Sprint(page))
+
+	parsed := &models.ParsedFeed{
+		Format:      Scheme,
+		Title:       r.Title,
+		Description: r.Description,
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < pages && pageURL != ""; i++ {
+		if seen[pageURL] {
+			break
+		}
+		seen[pageURL] = true
+		body, err := fetch(ctx, pageURL)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			parsed.Warnings = append(parsed.Warnings, fmt.Sprintf("Error fetching page %s: %v", pageURL, err))
+			break
+		}
+
+		var items []item
+		var next string
+		if r.Type == "json" {
+			items, next, err = r.scrapeJSON(body)
+		} else {
+			items, next, err = r.scrapeHTML(string(body))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error scraping %s: %v", pageURL, err)
+		}
+		if i == 0 {
+			parsed.Link = urlnorm.Normalize(pageURL, "")
+			if parsed.Title == "" {
+				parsed.Title = r.Name
+			}
+		}
+		if len(items) == 0 {
+			break
+		}
+		for _, it := range items {
+			article, err := r.article(it, pageURL)
+			if err != nil {
+				parsed.Warnings = append(parsed.Warnings, err.Error())
+				continue
+			}
+			parsed.Items = append(parsed.Items, article)
+		}
+
+		switch {
+		case r.Paging.Next != "":
+			pageURL = urlnorm.Normalize(next, pageURL)
+		case strings.Contains(r.URL, "{page}"):
+			page++
+			pageURL = strings.ReplaceAll(r.URL, "{page}", fmt.Sprint(page))
+		default:
+			pageURL = ""
+		}
+	}
+	return parsed, nil
+}
+
+// article turns a scraped item into an article. Items without a GUID are
+// identified by their link, and items with neither by a hash of their text.
+func (r *Rule) article(it item, pageURL string) (models.Article, error) {
+	published := time.Now()
+	if date := strings.TrimSpace(it["date"]); date != "" {
+		t, err := parseDate(date, r.DateFormat)
+		if err != nil {
+			return models.Article{}, fmt.Errorf("Error parsing date %s: %v", date, err)
+		}
+		published = t
+	}
+
+	link := urlnorm.Normalize(it["link"], pageURL)
+	guid := strings.TrimSpace(it["guid"])
+	if guid == "" {
+		guid = link
+	}
+	if guid == "" {
+		sum := sha256.Sum256([]byte(it["title"] + "\x00" + it["description"]))
+		guid = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	article := models.Article{
+		GUID:        guid,
+		Title:       strings.TrimSpace(it["title"]),
+		Link:        link,
+		Description: strings.TrimSpace(it["description"]),
+		Content:     strings.TrimSpace(it["content"]),
+		PublishedAt: published,
+	}
+	if author := strings.TrimSpace(it["author"]); author != "" {
+		article.Authors = []string{author}
+	}
+	return article, nil
+}
+
+// dateLayouts are tried, in order, for dates without a date_format.
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02.01.2006",
+}
+
+func parseDate(s, layout string) (time.Time, error) {
+	if layout == "unix" || (layout == "" && unixPattern.MatchString(s)) {
+		seconds, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	if layout != "" {
+		return time.Parse(layout, s)
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format")
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxPageSize {
+		return nil, fmt.Errorf("page larger than %d MB", maxPageSize>>20)
+	}
+	return charset.ToUTF8(body, resp.Header.Get("Content-Type"))
+}
+
+// Source generates route:// feeds from the rules in Dir.
+type Source struct {
+	Dir string
+}
+
+func (s Source) Fetch(ctx context.Context, feedURL string) (*models.ParsedFeed, error) {
+	rule, err := Load(s.Dir, Name(feedURL))
+	if err != nil {
+		return nil, err
+	}
+	return rule.Fetch(ctx)
+}
@@ -0,0 +1,249 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	for url, want := range map[string]string{
+		"route://example-news":  "example-news",
+		"route://example-news/": "example-news",
+		"https://example.com/":  "",
+	} {
+		if got := Name(url); got != want {
+			t.Errorf("Name(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	rules := map[string]string{
+		"ok":         `{"url": "https://example.com/", "items": "li", "fields": {"title": "a", "link": "a@href"}}`,
+		"json-ok":    `{"url": "https://example.com/api", "type": "json", "fields": {"title": "name"}}`,
+		"no-url":     `{"items": "li", "fields": {"title": "a"}}`,
+		"bad-type":   `{"url": "https://example.com/", "type": "xml", "items": "li", "fields": {"title": "a"}}`,
+		"no-items":   `{"url": "https://example.com/", "fields": {"title": "a"}}`,
+		"no-fields":  `{"url": "https://example.com/", "items": "li", "fields": {"date": "time"}}`,
+		"bad-field":  `{"url": "https://example.com/", "items": "li", "fields": {"title": "a:hover"}}`,
+		"bad-paging": `{"url": "https://example.com/", "items": "li", "fields": {"title": "a"}, "paging": {"next": "a["}}`,
+		"bad-json":   `{"url": `,
+	}
+	for name, data := range rules {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name := range rules {
+		rule, err := Load(dir, name)
+		if ok := strings.HasSuffix(name, "ok"); ok != (err == nil) {
+			t.Errorf("Load(%s) error = %v", name, err)
+			continue
+		}
+		if err == nil && (rule.Name != name || rule.Type == "") {
+			t.Errorf("Load(%s) = name %q, type %q", name, rule.Name, rule.Type)
+		}
+	}
+	if _, err := Load(dir, "../ok"); err == nil {
+		t.Errorf("Load accepted a path as name")
+	}
+	if _, err := Load(dir, "missing"); err == nil {
+		t.Errorf("Load of a missing rule succeeded")
+	}
+
+	names, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(rules) || names[0] != "bad-field" {
+		t.Errorf("List = %v", names)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		date, layout string
+		want         time.Time
+	}{
+		{"2025-01-20T15:04:05Z", "", time.Date(2025, 1, 20, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 20 Jan 2025 15:04:05 +0000", "", time.Date(2025, 1, 20, 15, 4, 5, 0, time.UTC)},
+		{"2025-01-20", "", time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"January 20, 2025", "", time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"20.01.2025", "", time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"1737385445", "", time.Date(2025, 1, 20, 15, 4, 5, 0, time.UTC)},
+		{"1737385445", "unix", time.Date(2025, 1, 20, 15, 4, 5, 0, time.UTC)},
+		{"20/01/2025", "02/01/2006", time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.date, tt.layout)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q, %q) = %v, %v, want %v", tt.date, tt.layout, got, err, tt.want)
+		}
+	}
+	for _, date := range []string{"yesterday", "20/01/2025"} {
+		if _, err := parseDate(date, ""); err == nil {
+			t.Errorf("parseDate(%q) succeeded", date)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	doc := map[string]any{
+		"data": map[string]any{
+			"posts": []any{
+				map[string]any{"id": float64(42), "links": []any{map[string]any{"href": "/a"}}},
+			},
+		},
+		"matrix": []any{[]any{"x", "y"}},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"data.posts[0].id", "42"},
+		{"data.posts[0].links[0].href", "/a"},
+		{"matrix[0][1]", "y"},
+		{"data.posts[1].id", ""},
+		{"data.missing.id", ""},
+		{"data.posts.id", ""},
+		{"matrix[x]", ""},
+		{"data.posts[0].links", `[{"href":"/a"}]`},
+	}
+	for _, tt := range tests {
+		if got := jsonString(lookup(doc, tt.path)); got != tt.want {
+			t.Errorf("lookup(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFetchHTML(t *testing.T) {
+	pages := map[string]string{
+		"/news": `<ul>
+  <li class="post"><a href="/a?utm_source=x">First &amp; best</a><time>2025-01-20</time><p class="summary">One</p></li>
+  <li class="post"><a href="https://other.example/b">Second</a><p class="summary"><b>Two</b></p></li>
+  <li class="post"><a href="/c">Third</a><time>soon</time></li>
+</ul><a class="next" href="/news?page=2">Next</a>`,
+		"/news?page=2": `<ul><li class="post"><a href="/d">Fourth</a></li></ul><a class="next" href="/news">Back</a>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+
+	rule := &Rule{
+		Name:  "news",
+		URL:   srv.URL + "/news",
+		Items: "li.post",
+		Fields: map[string]string{
+			"title":       "a",
+			"link":        "a@href",
+			"date":        "time",
+			"description": ".summary@html",
+		},
+		Paging: Paging{Next: "a.next@href", MaxPages: 5},
+	}
+	if err := rule.validate(); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := rule.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range parsed.Items {
+		got = append(got, a.Title+" | "+a.Link+" | "+a.Description)
+	}
+	want := []string{
+		"First & best | " + srv.URL + "/a | One",
+		"Second | https://other.example/b | <b>Two</b>",
+		"Fourth | " + srv.URL + "/d | ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("items =\n%q\nwant\n%q", got, want)
+	}
+	if len(parsed.Warnings) != 1 || !strings.Contains(parsed.Warnings[0], "soon") {
+		t.Errorf("warnings = %q, want one for the bad date", parsed.Warnings)
+	}
+	if first := parsed.Items[0]; first.GUID != first.Link || !first.PublishedAt.Equal(time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("first item = guid %q, published %v", first.GUID, first.PublishedAt)
+	}
+	if parsed.Title != "news" || parsed.Link != srv.URL+"/news" {
+		t.Errorf("feed = title %q, link %q", parsed.Title, parsed.Link)
+	}
+}
+
+func TestFetchJSON(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"data": {"posts": [{"name": "A", "url": "/a", "ts": 1737385445}, {"name": "B"}]}}`)
+		default:
+			fmt.Fprint(w, `{"data": {"posts": []}}`)
+		}
+	}))
+	defer srv.Close()
+
+	rule := &Rule{
+		Name:   "api",
+		URL:    srv.URL + "/api?page={page}",
+		Type:   "json",
+		Items:  "data.posts",
+		Fields: map[string]string{"title": "name", "link": "url", "date": "ts"},
+		Paging: Paging{MaxPages: 3},
+	}
+	parsed, err := rule.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(parsed.Items))
+	}
+	if a := parsed.Items[0]; a.Title != "A" || a.Link != srv.URL+"/a" || a.PublishedAt.Unix() != 1737385445 {
+		t.Errorf("first item = %q %q %v", a.Title, a.Link, a.PublishedAt)
+	}
+	// Items with neither a GUID nor a link are identified by their text.
+	if b := parsed.Items[1]; !strings.HasPrefix(b.GUID, "sha256:") {
+		t.Errorf("second item GUID = %q", b.GUID)
+	}
+	// Paging stops at the first empty page.
+	if want := []string{"/api?page=1", "/api?page=2"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad-json" {
+			fmt.Fprint(w, `{"posts": {}}`)
+			return
+		}
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer srv.Close()
+
+	for _, rule := range []*Rule{
+		{URL: srv.URL + "/missing", Type: "html", Items: "li", Fields: map[string]string{"title": "a"}},
+		{URL: srv.URL + "/bad-json", Type: "json", Items: "posts", Fields: map[string]string{"title": "name"}},
+	} {
+		if _, err := rule.Fetch(context.Background()); err == nil {
+			t.Errorf("Fetch(%s) succeeded", rule.URL)
+		}
+	}
+}
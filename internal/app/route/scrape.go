@@ -0,0 +1,141 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"rsshub/pkg/dom"
+)
+
+// compileField splits an HTML field into its selector and what to read
+// from the matched element: its text, its inner HTML ("html") or an
+// attribute.
+func compileField(field string) (*dom.Selector, string, error) {
+	selector, attr, _ := strings.Cut(field, "@")
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return nil, strings.TrimSpace(attr), nil // the item element itself
+	}
+	s, err := dom.Compile(selector)
+	return s, strings.TrimSpace(attr), err
+}
+
+// readField reads a field relative to an element.
+func readField(n *dom.Node, field string) string {
+	s, attr, err := compileField(field)
+	if err != nil {
+		return ""
+	}
+	if s != nil {
+		if n = n.Query(s); n == nil {
+			return ""
+		}
+	}
+	switch attr {
+	case "":
+		return strings.Join(strings.Fields(n.Text()), " ")
+	case "html":
+		return dom.RenderChildren(n)
+	default:
+		return n.Attr(attr)
+	}
+}
+
+func (r *Rule) scrapeHTML(page string) ([]item, string, error) {
+	doc := dom.Parse(page)
+	s, err := dom.Compile(r.Items)
+	if err != nil {
+		return nil, "", err
+	}
+	var items []item
+	for _, n := range doc.QueryAll(s) {
+		it := make(item)
+		for key, field := range r.Fields {
+			it[key] = readField(n, field)
+		}
+		items = append(items, it)
+	}
+	var next string
+	if r.Paging.Next != "" {
+		next = readField(doc, r.Paging.Next)
+	}
+	return items, next, nil
+}
+
+func (r *Rule) scrapeJSON(body []byte) ([]item, string, error) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, "", err
+	}
+	list := doc
+	if r.Items != "" {
+		list = lookup(doc, r.Items)
+	}
+	values, ok := list.([]any)
+	if !ok {
+		return nil, "", fmt.Errorf("items path %q is not an array", r.Items)
+	}
+
+	var items []item
+	for _, v := range values {
+		it := make(item)
+		for key, path := range r.Fields {
+			it[key] = jsonString(lookup(v, path))
+		}
+		items = append(items, it)
+	}
+	var next string
+	if r.Paging.Next != "" {
+		next = jsonString(lookup(doc, r.Paging.Next))
+	}
+	return items, next, nil
+}
+
+// lookup follows a dotted path with optional [index] steps, such as
+// "data.posts" or "links[0].href", returning nil if it does not exist.
+func lookup(v any, path string) any {
+	for _, step := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(step, "[")
+		if key != "" {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil
+			}
+			v = obj[key]
+		}
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil
+			}
+			i, err := strconv.Atoi(rest[:end])
+			list, ok := v.([]any)
+			if err != nil || !ok || i < 0 || i >= len(list) {
+				return nil
+			}
+			v = list[i]
+			rest = strings.TrimPrefix(rest[end+1:], "[")
+		}
+	}
+	return v
+}
+
+// jsonString formats a scalar JSON value; numbers that are whole, such as
+// Unix timestamps and IDs, are written without a fraction.
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,172 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/charset"
+	"rsshub/pkg/dom"
+	"rsshub/pkg/tracing"
+)
+
+// feedTypes are the MIME types of feeds advertised with
+// <link rel="alternate">.
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+}
+
+// commonFeedPaths are probed when a page does not advertise its feeds.
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/feed.xml", "/atom.xml", "/index.xml", "/rss"}
+
+const (
+	// fetchTimeout bounds the request for one feed or page, body included.
+	fetchTimeout = 30 * time.Second
+	// maxFeedSize caps the bytes read from a feed or page.
+	maxFeedSize = 10 << 20
+)
+
+var client = &http.Client{Timeout: fetchTimeout}
+
+// Candidate is a feed found for a site, already fetched and parsed.
+type Candidate struct {
+	URL   string
+	Title string // from the <link> element, if any
+	Feed  *models.ParsedFeed
+}
+
+// Discover finds the feeds of the page at pageURL. If the URL is a feed
+// itself it is the only candidate. Otherwise the feeds advertised by the
+// page's <link rel="alternate"> elements are returned, or if there are none
+// the feeds found at common paths such as /feed and /rss.xml. Only
+// candidates that parse as feeds are returned.
+func Discover(ctx context.Context, pageURL string) ([]Candidate, error) {
+	body, finalURL, err := fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if feed, err := Parse(body, finalURL); err == nil {
+		return []Candidate{{URL: finalURL, Feed: feed}}, nil
+	}
+
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	try := func(link, title string) {
+		if seen[link] {
+			return
+		}
+		seen[link] = true
+		body, feedURL, err := fetch(ctx, link)
+		if err != nil {
+			return
+		}
+		feed, err := Parse(body, feedURL)
+		if err != nil {
+			return
+		}
+		candidates = append(candidates, Candidate{URL: link, Title: title, Feed: feed})
+	}
+
+	for _, link := range alternateLinks(string(body), finalURL) {
+		try(link.URL, link.Title)
+	}
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	base, err := url.Parse(finalURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range commonFeedPaths {
+		try(base.ResolveReference(&url.URL{Path: path}).String(), "")
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no feed found at %s", pageURL)
+	}
+	return candidates, nil
+}
+
+// alternateLinks returns the feeds advertised in the page's <head>,
+// resolved against its <base> or URL.
+func alternateLinks(page, pageURL string) []Candidate {
+	doc := dom.Parse(page)
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	if b := doc.Find("base"); b != nil && b.Attr("href") != "" {
+		if u, err := base.Parse(b.Attr("href")); err == nil {
+			base = u
+		}
+	}
+
+	var links []Candidate
+	for _, link := range doc.FindAll("link") {
+		rels := strings.Fields(strings.ToLower(link.Attr("rel")))
+		mimeType := strings.ToLower(strings.TrimSpace(link.Attr("type")))
+		if !containsString(rels, "alternate") || !feedTypes[mimeType] || link.Attr("href") == "" {
+			continue
+		}
+		u, err := base.Parse(strings.TrimSpace(link.Attr("href")))
+		if err != nil {
+			continue
+		}
+		links = append(links, Candidate{URL: u.String(), Title: strings.TrimSpace(link.Attr("title"))})
+	}
+	return links
+}
+
+// fetch downloads url and returns its body, converted to UTF-8, and the URL
+// it was served from after redirects.
+func fetch(ctx context.Context, url string) (body []byte, finalURL string, err error) {
+	ctx, span := tracing.StartClient(ctx, "http.fetch", "http.method", http.MethodGet, "http.url", url)
+	defer func() {
+		span.Set("http.response.size", len(body))
+		span.RecordError(err)
+		span.End()
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	span.Set("http.status_code", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err = io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxFeedSize {
+		return nil, "", fmt.Errorf("response larger than %d MB", maxFeedSize>>20)
+	}
+	body, err = charset.ToUTF8(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Request.URL.String(), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
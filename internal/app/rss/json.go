@@ -0,0 +1,102 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	models "rsshub/internal/domain"
+	"rsshub/pkg/urlnorm"
+)
+
+func parseJSONFeed(body []byte, feedURL string) (*models.ParsedFeed, error) {
+	var feed models.JSONFeed
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("unsupported feed format: JSON without a JSON Feed version")
+	}
+
+	base := urlnorm.Normalize(feed.HomePageURL, feedURL)
+	if base == "" {
+		base = feedURL
+	}
+	icon := feed.Favicon
+	if icon == "" {
+		icon = feed.Icon
+	}
+	parsed := &models.ParsedFeed{
+		Format:      "json",
+		Title:       strings.TrimSpace(feed.Title),
+		Link:        base,
+		Description: strings.TrimSpace(feed.Description),
+		Language:    strings.TrimSpace(feed.Language),
+		IconURL:     iconURL(icon, base),
+		Self:        resolve(feed.FeedURL, feedURL),
+	}
+	for _, hub := range feed.Hubs {
+		if strings.EqualFold(hub.Type, "websub") || strings.EqualFold(hub.Type, "pubsubhubbub") {
+			if u := resolve(hub.URL, feedURL); u != "" {
+				parsed.Hubs = append(parsed.Hubs, u)
+			}
+		}
+	}
+
+	for _, item := range feed.Items {
+		date := item.DatePublished
+		if date == "" {
+			date = item.DateModified
+		}
+		published, err := parseAtomDate(date)
+		if err != nil {
+			parsed.Warnings = append(parsed.Warnings, fmt.Sprintf("Error parsing date %s: %v", date, err))
+			continue
+		}
+
+		content := strings.TrimSpace(item.ContentHTML)
+		if content == "" && item.ContentText != "" {
+			content = "<p>" + strings.ReplaceAll(html.EscapeString(strings.TrimSpace(item.ContentText)), "\n\n", "</p><p>") + "</p>"
+		}
+		description := item.Summary
+		if description == "" {
+			description = content
+		}
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		var authors []string
+		if item.Author != nil {
+			authors = append(authors, item.Author.Name)
+		}
+		for _, author := range append(item.Authors, feed.Authors...) {
+			authors = append(authors, author.Name)
+		}
+
+		var media []models.Media
+		for _, a := range item.Attachments {
+			media = append(media, models.Media{
+				URL:          a.URL,
+				MIMEType:     a.MIMEType,
+				Length:       a.SizeInBytes,
+				Duration:     int(a.DurationInSeconds),
+				ThumbnailURL: item.Image,
+			})
+		}
+
+		guid := ""
+		if item.ID != nil {
+			guid = strings.TrimSpace(fmt.Sprint(item.ID))
+		}
+		article := newArticle(guid, item.Title, link, description, published, base)
+		article.Content = content
+		article.Authors = uniqueStrings(authors)
+		article.Categories = uniqueStrings(item.Tags)
+		article.Media = normalizeMedia(media, base)
+		parsed.Items = append(parsed.Items, article)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,168 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"regexp"
+	"unicode/utf8"
+)
+
+// entityPattern matches what may follow an & in a well-formed document: a
+// named, decimal or hexadecimal character reference.
+var entityPattern = regexp.MustCompile(`^&(#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{0,31});`)
+
+// xmlEntities are the only named entities XML defines.
+var xmlEntities = map[string]bool{"amp": true, "lt": true, "gt": true, "quot": true, "apos": true}
+
+// decodeXML unmarshals a feed document into v, which must be a pointer.
+// Documents that are not well-formed are repaired and decoded leniently,
+// and what had to be fixed is returned as warnings. If even that fails the
+// items decoded before the error are kept, unless nothing could be decoded.
+func decodeXML(body []byte, v any) ([]string, error) {
+	strictErr := xml.Unmarshal(body, v)
+	if strictErr == nil {
+		return nil, nil
+	}
+
+	target := reflect.ValueOf(v).Elem()
+	target.SetZero()
+	repaired, fixes := repair(body)
+	warnings := []string{fmt.Sprintf("Malformed XML, parsed leniently: %v", strictErr)}
+	warnings = append(warnings, fixes...)
+
+	d := xml.NewDecoder(bytes.NewReader(repaired))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	if err := d.Decode(v); err != nil {
+		if target.IsZero() {
+			return nil, strictErr
+		}
+		warnings = append(warnings, fmt.Sprintf("Malformed XML, kept what could be parsed: %v", err))
+	}
+	return warnings, nil
+}
+
+// repair fixes the defects most often found in real-world feeds: invalid
+// UTF-8 and control characters, bare ampersands, HTML entities that XML
+// does not define and unterminated CDATA sections. It returns the repaired
+// document and a description of each kind of fix made.
+func repair(body []byte) ([]byte, []string) {
+	body, invalid := dropInvalidChars(body)
+
+	var out bytes.Buffer
+	out.Grow(len(body) + len(body)/16)
+	var ampersands, entities, cdata int
+	lastTag := ""
+	for i := 0; i < len(body); {
+		rest := body[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			end := bytes.Index(rest[len("<![CDATA["):], []byte("]]>"))
+			next := bytes.Index(rest[len("<![CDATA["):], []byte("<![CDATA["))
+			if end >= 0 && (next < 0 || end < next) {
+				n := len("<![CDATA[") + end + len("]]>")
+				out.Write(rest[:n])
+				i += n
+				continue
+			}
+			// Close the section before the end tag of the element it is in.
+			n := len(rest)
+			if lastTag != "" {
+				if k := bytes.Index(rest, []byte("</"+lastTag)); k >= 0 {
+					n = k
+				}
+			}
+			out.Write(rest[:n])
+			out.WriteString("]]>")
+			cdata++
+			i += n
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			n := len(rest)
+			if end := bytes.Index(rest[4:], []byte("-->")); end >= 0 {
+				n = 4 + end + 3
+			}
+			out.Write(rest[:n])
+			i += n
+		case rest[0] == '<' && len(rest) > 1 && isNameStart(rest[1]):
+			n := 1
+			for n < len(rest) && isNameChar(rest[n]) {
+				n++
+			}
+			lastTag = string(rest[1:n])
+			out.Write(rest[:n])
+			i += n
+		case rest[0] == '&':
+			m := entityPattern.Find(rest)
+			switch {
+			case m == nil:
+				out.WriteString("&amp;")
+				ampersands++
+				i++
+				continue
+			case m[1] == '#' || xmlEntities[string(m[1:len(m)-1])]:
+				out.Write(m)
+			case xml.HTMLEntity[string(m[1:len(m)-1])] != "":
+				for _, r := range xml.HTMLEntity[string(m[1:len(m)-1])] {
+					fmt.Fprintf(&out, "&#%d;", r)
+				}
+				entities++
+			default:
+				out.WriteString("&amp;")
+				out.Write(m[1:])
+				ampersands++
+			}
+			i += len(m)
+		default:
+			out.WriteByte(rest[0])
+			i++
+		}
+	}
+
+	var fixes []string
+	if invalid > 0 {
+		fixes = append(fixes, fmt.Sprintf("Malformed XML: removed %d invalid characters", invalid))
+	}
+	if ampersands > 0 {
+		fixes = append(fixes, fmt.Sprintf("Malformed XML: escaped %d bare ampersands", ampersands))
+	}
+	if entities > 0 {
+		fixes = append(fixes, fmt.Sprintf("Malformed XML: replaced %d HTML entities", entities))
+	}
+	if cdata > 0 {
+		fixes = append(fixes, fmt.Sprintf("Malformed XML: closed %d unterminated CDATA sections", cdata))
+	}
+	return out.Bytes(), fixes
+}
+
+// dropInvalidChars removes bytes that are not valid UTF-8 and characters
+// that may not appear in XML, such as control characters other than tab,
+// newline and carriage return.
+func dropInvalidChars(body []byte) ([]byte, int) {
+	out := make([]byte, 0, len(body))
+	dropped := 0
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRune(body[i:])
+		if (r == utf8.RuneError && size == 1) || !isXMLChar(r) {
+			dropped++
+		} else {
+			out = append(out, body[i:i+size]...)
+		}
+		i += size
+	}
+	return out, dropped
+}
+
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xd7ff) || (r >= 0xe000 && r <= 0xfffd) || (r >= 0x10000 && r <= 0x10ffff)
+}
+
+func isNameStart(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b == '_'
+}
+
+func isNameChar(b byte) bool {
+	return isNameStart(b) || b >= '0' && b <= '9' || b == '-' || b == '.' || b == ':'
+}
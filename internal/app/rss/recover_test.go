@@ -0,0 +1,84 @@
+package rss
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	models "rsshub/internal/domain"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		want  string
+		fixes []string
+	}{
+		{"well-formed", `<a href="x?a=1&amp;b=2">&lt;&#233;&#xe9;</a>`, `<a href="x?a=1&amp;b=2">&lt;&#233;&#xe9;</a>`, nil},
+		{"bare ampersand", `<t>Q&A & more</t>`, `<t>Q&amp;A &amp; more</t>`,
+			[]string{"Malformed XML: escaped 2 bare ampersands"}},
+		{"unknown entity", `<t>&bogus; &x</t>`, `<t>&amp;bogus; &amp;x</t>`,
+			[]string{"Malformed XML: escaped 2 bare ampersands"}},
+		{"HTML entities", `<t>&nbsp;&eacute;&hellip;</t>`, `<t>&#160;&#233;&#8230;</t>`,
+			[]string{"Malformed XML: replaced 3 HTML entities"}},
+		{"invalid characters", "<t>a\x00b\x1fc\xffd\te</t>", "<t>abcd\te</t>",
+			[]string{"Malformed XML: removed 3 invalid characters"}},
+		{"unterminated CDATA", `<d><![CDATA[<p>hi</p></d><e/>`, `<d><![CDATA[<p>hi</p>]]></d><e/>`,
+			[]string{"Malformed XML: closed 1 unterminated CDATA sections"}},
+		{"CDATA before another", `<d><![CDATA[one</d><d><![CDATA[two]]></d>`, `<d><![CDATA[one]]></d><d><![CDATA[two]]></d>`,
+			[]string{"Malformed XML: closed 1 unterminated CDATA sections"}},
+		{"CDATA contents kept", `<d><![CDATA[a & b &nbsp;]]></d>`, `<d><![CDATA[a & b &nbsp;]]></d>`, nil},
+		{"comment contents kept", `<!-- a & b --><t/>`, `<!-- a & b --><t/>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fixes := repair([]byte(tt.in))
+			if string(got) != tt.want {
+				t.Errorf("repair(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if !reflect.DeepEqual(fixes, tt.fixes) {
+				t.Errorf("fixes = %q, want %q", fixes, tt.fixes)
+			}
+		})
+	}
+}
+
+func TestDecodeXML(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		titles   []string
+		warnings int
+		wantErr  bool
+	}{
+		{"well-formed", `<rss><channel><item><title>A &amp; B</title></item></channel></rss>`, []string{"A & B"}, 0, false},
+		{"repaired", `<rss><channel><item><title>A & B &mdash; C</title></item></channel></rss>`, []string{"A & B — C"}, 3, false},
+		{"truncated", `<rss><channel><item><title>One</title></item><item><title>Tw`, []string{"One"}, 2, false},
+		{"not XML", `hello`, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var feed models.RSSFeed
+			warnings, err := decodeXML([]byte(tt.in), &feed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			var titles []string
+			for _, item := range feed.Channel.Item {
+				titles = append(titles, item.Title)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(titles, tt.titles) {
+				t.Errorf("titles = %q, want %q", titles, tt.titles)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("warnings = %q, want %d", warnings, tt.warnings)
+			}
+			for _, w := range warnings {
+				if !strings.HasPrefix(w, "Malformed XML") {
+					t.Errorf("warning %q", w)
+				}
+			}
+		})
+	}
+}
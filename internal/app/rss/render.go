@@ -0,0 +1,157 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	models "rsshub/internal/domain"
+)
+
+// The output structs spell namespaced elements with their prefix, which
+// encoding/xml writes out verbatim; the prefixes are declared on <rss>.
+type rssOutput struct {
+	XMLName   xml.Name         `xml:"rss"`
+	Version   string           `xml:"version,attr"`
+	MediaNS   string           `xml:"xmlns:media,attr"`
+	ITunesNS  string           `xml:"xmlns:itunes,attr"`
+	DCNS      string           `xml:"xmlns:dc,attr"`
+	ContentNS string           `xml:"xmlns:content,attr"`
+	Channel   rssOutputChannel `xml:"channel"`
+}
+
+type rssOutputChannel struct {
+	Title         string          `xml:"title"`
+	Link          string          `xml:"link"`
+	Description   string          `xml:"description"`
+	Language      string          `xml:"language,omitempty"`
+	Generator     string          `xml:"generator"`
+	LastBuildDate string          `xml:"lastBuildDate"`
+	Image         *rssOutputImage `xml:"image,omitempty"`
+	Items         []rssOutputItem `xml:"item"`
+}
+
+type rssOutputImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssOutputItem struct {
+	Title       string                  `xml:"title"`
+	Link        string                  `xml:"link,omitempty"`
+	Description string                  `xml:"description,omitempty"`
+	Content     string                  `xml:"content:encoded,omitempty"`
+	Creators    []string                `xml:"dc:creator"`
+	Categories  []string                `xml:"category"`
+	GUID        rssOutputGUID           `xml:"guid"`
+	PubDate     string                  `xml:"pubDate"`
+	Enclosure   *rssOutputEnclosure     `xml:"enclosure,omitempty"`
+	Duration    string                  `xml:"itunes:duration,omitempty"`
+	Image       *rssOutputITunesImage   `xml:"itunes:image,omitempty"`
+	Media       []rssOutputMediaContent `xml:"media:content"`
+}
+
+type rssOutputGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+type rssOutputEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssOutputITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssOutputMediaContent struct {
+	URL       string                   `xml:"url,attr"`
+	Type      string                   `xml:"type,attr,omitempty"`
+	FileSize  int64                    `xml:"fileSize,attr,omitempty"`
+	Duration  int                      `xml:"duration,attr,omitempty"`
+	Thumbnail *rssOutputMediaThumbnail `xml:"media:thumbnail,omitempty"`
+}
+
+type rssOutputMediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// Render writes the feed and its articles as an RSS 2.0 document. Article
+// media is emitted as an enclosure (the first file) plus Media RSS and
+// iTunes elements, so that podcast clients can play it.
+func Render(feed models.Feed, articles []models.Article) ([]byte, error) {
+	channel := rssOutputChannel{
+		Title:         feed.Title,
+		Link:          feed.SiteURL,
+		Description:   feed.Description,
+		Language:      feed.Language,
+		Generator:     "rsshub",
+		LastBuildDate: time.Now().Format(time.RFC1123Z),
+	}
+	if channel.Title == "" {
+		channel.Title = feed.Name
+	}
+	if channel.Link == "" {
+		channel.Link = feed.URL
+	}
+	if feed.IconURL != "" {
+		channel.Image = &rssOutputImage{URL: feed.IconURL, Title: channel.Title, Link: channel.Link}
+	}
+
+	for _, a := range articles {
+		// Text extracted from the article's page is the fullest version.
+		body := a.Content
+		if a.FullContent != "" {
+			body = a.FullContent
+		}
+		item := rssOutputItem{
+			Title:       a.Title,
+			Link:        a.Link,
+			Description: a.Description,
+			Content:     body,
+			Creators:    a.Authors,
+			Categories:  a.Categories,
+			GUID:        rssOutputGUID{Value: a.GUID, IsPermaLink: strconv.FormatBool(a.GUID == a.Link)},
+			PubDate:     a.PublishedAt.Format(time.RFC1123Z),
+		}
+		for i, m := range a.Media {
+			if i == 0 {
+				item.Enclosure = &rssOutputEnclosure{URL: m.URL, Length: m.Length, Type: m.MIMEType}
+				if m.Duration > 0 {
+					item.Duration = formatDuration(m.Duration)
+				}
+				if m.ThumbnailURL != "" {
+					item.Image = &rssOutputITunesImage{Href: m.ThumbnailURL}
+				}
+			}
+			content := rssOutputMediaContent{URL: m.URL, Type: m.MIMEType, FileSize: m.Length, Duration: m.Duration}
+			if m.ThumbnailURL != "" {
+				content.Thumbnail = &rssOutputMediaThumbnail{URL: m.ThumbnailURL}
+			}
+			item.Media = append(item.Media, content)
+		}
+		channel.Items = append(channel.Items, item)
+	}
+
+	out, err := xml.MarshalIndent(rssOutput{
+		Version:   "2.0",
+		MediaNS:   models.MediaNS,
+		ITunesNS:  models.ITunesNS,
+		DCNS:      models.DCNS,
+		ContentNS: models.ContentNS,
+		Channel:   channel,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// formatDuration formats seconds as H:MM:SS, as expected by itunes:duration.
+func formatDuration(seconds int) string {
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
@@ -1,29 +1,493 @@
 package rss
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
-	"io"
-	"net/http"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	models "rsshub/internal/domain"
+	"rsshub/pkg/tracing"
+	"rsshub/pkg/urlnorm"
 )
 
-func FetchAndParse(url string) (*models.RSSFeed, error) {
-	resp, err := http.Get(url)
+func FetchAndParse(ctx context.Context, url string) (*models.ParsedFeed, error) {
+	body, _, err := fetch(ctx, url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	_, span := tracing.Start(ctx, "feed.parse", "bytes", len(body))
+	defer span.End()
+	parsed, err := Parse(body, url)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.Set("items", len(parsed.Items), "warnings", len(parsed.Warnings))
+	return parsed, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// Parse detects whether body is RSS 2.0, Atom or JSON Feed and normalizes
+// it. The body must already be UTF-8: whoever reads it converts it once
+// with charset.ToUTF8, which also takes the Content-Type of HTTP responses
+// into account. Relative links are resolved against the channel link, or
+// feedURL if there is none.
+func Parse(body []byte, feedURL string) (*models.ParsedFeed, error) {
+	if trimmed := bytes.TrimLeft(body, "\ufeff \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseJSONFeed(trimmed, feedURL)
+	}
+	root, err := rootElement(body)
 	if err != nil {
 		return nil, err
 	}
+	switch root {
+	case "rss":
+		return parseRSS(body, feedURL)
+	case "feed":
+		return parseAtom(body, feedURL)
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root)
+	}
+}
+
+func rootElement(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
 
+func parseRSS(body []byte, feedURL string) (*models.ParsedFeed, error) {
 	var feed models.RSSFeed
-	err = xml.Unmarshal(body, &feed)
+	warnings, err := decodeXML(body, &feed)
+	if err != nil {
+		return nil, err
+	}
+
+	base := urlnorm.Normalize(feed.Channel.Link, feedURL)
+	if base == "" {
+		base = feedURL
+	}
+	ttl, _ := strconv.Atoi(strings.TrimSpace(feed.Channel.TTL))
+	channelImage := feed.Channel.Image.URL
+	if channelImage == "" {
+		channelImage = feed.Channel.ITunesImage.Href
+	}
+	parsed := &models.ParsedFeed{
+		Format:        "rss",
+		Title:         strings.TrimSpace(feed.Channel.Title),
+		Link:          base,
+		Description:   strings.TrimSpace(feed.Channel.Description),
+		Language:      strings.TrimSpace(feed.Channel.Language),
+		IconURL:       iconURL(channelImage, base),
+		LastBuildDate: parseMetaDate(feed.Channel.LastBuildDate),
+		Generator:     strings.TrimSpace(feed.Channel.Generator),
+		TTL:           ttl,
+		Hubs:          linksByRel(feed.Channel.AtomLinks, "hub", feedURL),
+		Self:          firstString(linksByRel(feed.Channel.AtomLinks, "self", feedURL)),
+		Warnings:      warnings,
+	}
+
+	for _, item := range feed.Channel.Item {
+		pubDate, err := parseDate(item.PubDate)
+		if err != nil {
+			parsed.Warnings = append(parsed.Warnings, fmt.Sprintf("Error parsing date %s: %v", item.PubDate, err))
+			continue
+		}
+
+		guid := strings.TrimSpace(item.GUID.Value)
+		link := item.Link
+		if link == "" && item.GUID.IsPermaLink != "false" && (strings.HasPrefix(guid, "http://") || strings.HasPrefix(guid, "https://")) {
+			link = guid
+		}
+		description := item.Description
+		if description == "" {
+			description = item.ITunesSummary
+		}
+
+		thumbnail := item.ITunesImage.Href
+		if len(item.MediaThumbnails) > 0 {
+			thumbnail = item.MediaThumbnails[0].URL
+		}
+		var media []models.Media
+		for _, enc := range item.Enclosures {
+			length, _ := strconv.ParseInt(strings.TrimSpace(enc.Length), 10, 64)
+			media = append(media, models.Media{
+				URL:          enc.URL,
+				MIMEType:     enc.Type,
+				Length:       length,
+				Duration:     parseDuration(item.ITunesDuration),
+				ThumbnailURL: thumbnail,
+			})
+		}
+		media = append(media, mediaRSS(item.MediaContents, item.MediaGroups, thumbnail)...)
+
+		authors := append([]string{}, item.Creators...)
+		authors = append(authors, rssAuthor(item.Author))
+		if len(uniqueStrings(authors)) == 0 {
+			authors = append(authors, item.ITunesAuthor)
+		}
+
+		article := newArticle(guid, item.Title, link, description, pubDate, base)
+		article.Content = strings.TrimSpace(item.ContentEncoded)
+		article.Authors = uniqueStrings(authors)
+		article.Categories = uniqueStrings(item.Categories)
+		article.Media = normalizeMedia(media, base)
+		parsed.Items = append(parsed.Items, article)
+	}
+	return parsed, nil
+}
+
+func parseAtom(body []byte, feedURL string) (*models.ParsedFeed, error) {
+	var feed models.AtomFeed
+	warnings, err := decodeXML(body, &feed)
 	if err != nil {
 		return nil, err
 	}
-	return &feed, nil
+
+	base := urlnorm.Normalize(getAtomLink(feed.Link), feedURL)
+	if base == "" {
+		base = feedURL
+	}
+	icon := feed.Icon
+	if icon == "" {
+		icon = feed.Logo
+	}
+	parsed := &models.ParsedFeed{
+		Format:        "atom",
+		Title:         strings.TrimSpace(feed.Title),
+		Link:          base,
+		Description:   strings.TrimSpace(feed.Subtitle),
+		Language:      strings.TrimSpace(feed.Lang),
+		IconURL:       iconURL(icon, base),
+		LastBuildDate: parseMetaDate(feed.Updated),
+		Generator:     strings.TrimSpace(feed.Generator),
+		Hubs:          linksByRel(feed.Link, "hub", feedURL),
+		Self:          firstString(linksByRel(feed.Link, "self", feedURL)),
+		Warnings:      warnings,
+	}
+
+	for _, entry := range feed.Entries {
+		content := atomContentHTML(entry.Content)
+		description := entry.Summary
+		if description == "" {
+			description = content
+		}
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+
+		pubTime, err := parseAtomDate(pubDate)
+		if err != nil {
+			parsed.Warnings = append(parsed.Warnings, fmt.Sprintf("Error parsing date %s: %v", pubDate, err))
+			continue
+		}
+
+		thumbnail := ""
+		if len(entry.MediaThumbnails) > 0 {
+			thumbnail = entry.MediaThumbnails[0].URL
+		}
+		var media []models.Media
+		for _, link := range entry.Link {
+			if link.Rel != "enclosure" || link.Href == "" {
+				continue
+			}
+			length, _ := strconv.ParseInt(strings.TrimSpace(link.Length), 10, 64)
+			media = append(media, models.Media{URL: link.Href, MIMEType: link.Type, Length: length, ThumbnailURL: thumbnail})
+		}
+		media = append(media, mediaRSS(entry.MediaContents, entry.MediaGroups, thumbnail)...)
+
+		var authors, categories []string
+		for _, author := range entry.Authors {
+			if author.Name != "" {
+				authors = append(authors, author.Name)
+			} else {
+				authors = append(authors, author.Email)
+			}
+		}
+		for _, category := range entry.Categories {
+			if category.Label != "" {
+				categories = append(categories, category.Label)
+			} else {
+				categories = append(categories, category.Term)
+			}
+		}
+
+		article := newArticle(strings.TrimSpace(entry.ID), entry.Title, getAtomLink(entry.Link), description, pubTime, base)
+		article.Content = content
+		article.Authors = uniqueStrings(authors)
+		article.Categories = uniqueStrings(categories)
+		article.Media = normalizeMedia(media, base)
+		parsed.Items = append(parsed.Items, article)
+	}
+	return parsed, nil
+}
+
+// newArticle builds an article with a canonical link and a stable GUID.
+// Items without a GUID are identified by their canonical link, and items
+// with neither by a hash of their text.
+func newArticle(guid, title, link, description string, published time.Time, base string) models.Article {
+	link = urlnorm.Normalize(link, base)
+	if guid == "" {
+		guid = link
+	}
+	if guid == "" {
+		sum := sha256.Sum256([]byte(title + "\x00" + description))
+		guid = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return models.Article{
+		GUID:        guid,
+		Title:       title,
+		Link:        link,
+		Description: description,
+		PublishedAt: published,
+	}
+}
+
+// atomContentHTML returns Atom content as HTML: html content as is, xhtml
+// content without its wrapping <div>, and text content escaped.
+func atomContentHTML(c models.AtomContent) string {
+	switch c.Type {
+	case "html", "text/html":
+		return strings.TrimSpace(c.Text)
+	case "xhtml", "application/xhtml+xml":
+		inner := strings.TrimSpace(c.Inner)
+		if strings.HasPrefix(inner, "<div") && strings.HasSuffix(inner, "</div>") {
+			if start := strings.Index(inner, ">"); start >= 0 {
+				inner = inner[start+1 : len(inner)-len("</div>")]
+			}
+		}
+		return strings.TrimSpace(inner)
+	default:
+		return html.EscapeString(strings.TrimSpace(c.Text))
+	}
+}
+
+// rssAuthor extracts the name from an RSS <author>, which is an email
+// address optionally followed by the name in parentheses.
+func rssAuthor(s string) string {
+	s = strings.TrimSpace(s)
+	if open := strings.Index(s, "("); open > 0 && strings.HasSuffix(s, ")") {
+		if name := strings.TrimSpace(s[open+1 : len(s)-1]); name != "" {
+			return name
+		}
+	}
+	return s
+}
+
+// uniqueStrings trims the values and drops empty ones and repeats.
+func uniqueStrings(values []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		result = append(result, v)
+	}
+	return result
+}
+
+// mediaRSS converts Media RSS content elements, loose or grouped, falling
+// back to thumbnail for those without a thumbnail of their own.
+func mediaRSS(contents []models.MediaContent, groups []models.MediaGroup, thumbnail string) []models.Media {
+	var media []models.Media
+	add := func(c models.MediaContent, thumbnail string) {
+		if len(c.Thumbnails) > 0 {
+			thumbnail = c.Thumbnails[0].URL
+		}
+		mimeType := c.Type
+		if mimeType == "" && c.Medium != "" {
+			mimeType = c.Medium
+		}
+		length, _ := strconv.ParseInt(strings.TrimSpace(c.FileSize), 10, 64)
+		media = append(media, models.Media{
+			URL:          c.URL,
+			MIMEType:     mimeType,
+			Length:       length,
+			Duration:     parseDuration(c.Duration),
+			ThumbnailURL: thumbnail,
+		})
+	}
+
+	for _, c := range contents {
+		add(c, thumbnail)
+	}
+	for _, g := range groups {
+		groupThumbnail := thumbnail
+		if len(g.Thumbnails) > 0 {
+			groupThumbnail = g.Thumbnails[0].URL
+		}
+		for _, c := range g.Contents {
+			add(c, groupThumbnail)
+		}
+	}
+	return media
+}
+
+// normalizeMedia resolves media URLs against base and drops entries without
+// a URL and repeats of the same file, which feeds often list both as an
+// enclosure and as Media RSS content.
+func normalizeMedia(media []models.Media, base string) []models.Media {
+	var result []models.Media
+	seen := make(map[string]bool)
+	for _, m := range media {
+		m.URL = urlnorm.Normalize(m.URL, base)
+		if m.URL == "" || seen[m.URL] {
+			continue
+		}
+		seen[m.URL] = true
+		m.MIMEType = strings.TrimSpace(m.MIMEType)
+		m.ThumbnailURL = urlnorm.Normalize(m.ThumbnailURL, base)
+		result = append(result, m)
+	}
+	return result
+}
+
+// parseDuration parses a duration given in seconds or as [HH:]MM:SS, as
+// used by itunes:duration and media:content, returning 0 if it is invalid.
+func parseDuration(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	seconds := 0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + int(n)
+	}
+	return seconds
+}
+
+// iconURL resolves the feed image against the site, falling back to the
+// site's /favicon.ico.
+func iconURL(image, site string) string {
+	if image = urlnorm.Normalize(image, site); image != "" {
+		return image
+	}
+	u, err := url.Parse(site)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/favicon.ico"
+}
+
+// dateLayouts are the date formats found in feeds: RFC 822 as updated by
+// RFC 1123, with or without a leading zero in the day and with a numeric or
+// named zone, and RFC 3339. The day of the week is removed before parsing.
+var dateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04:05 MST",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses the date of an item or channel in any of dateLayouts.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		s = strings.TrimSpace(s[i+1:])
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unrecognized date format")
+}
+
+// parseMetaDate parses a channel-level date, returning the zero time for
+// missing or unparseable values.
+func parseMetaDate(s string) time.Time {
+	t, _ := parseDate(s)
+	return t
+}
+
+// linksByRel returns the URLs of the links with the given rel, resolved
+// against base.
+func linksByRel(links []models.AtomLink, rel, base string) []string {
+	var urls []string
+	for _, link := range links {
+		if !containsString(strings.Fields(strings.ToLower(link.Rel)), rel) {
+			continue
+		}
+		if u := resolve(link.Href, base); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// resolve resolves ref against base without normalizing it, for URLs such
+// as WebSub topics that must be kept exactly as published.
+func resolve(ref, base string) string {
+	ref = strings.TrimSpace(ref)
+	b, err := url.Parse(base)
+	if ref == "" || err != nil {
+		return ref
+	}
+	u, err := b.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func firstString(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func getAtomLink(links []models.AtomLink) string {
+	// Try to find the main content link (prefer alternate link)
+	for _, link := range links {
+		if (link.Rel == "alternate" || link.Rel == "") && link.Href != "" {
+			return link.Href
+		}
+	}
+
+	// Fallback to any link with href
+	for _, link := range links {
+		if link.Href != "" {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
+func parseAtomDate(dateStr string) (time.Time, error) {
+	if dateStr == "" {
+		return time.Now(), nil
+	}
+
+	return parseDate(dateStr)
 }
@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2025, time.January, 5, 10, 45, 7, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Sun, 05 Jan 2025 10:45:07 GMT", want},
+		{"Sun, 5 Jan 2025 10:45:07 GMT", want},
+		{"Sun, 05 Jan 2025 10:45:07 +0000", want},
+		{"Sun, 5 Jan 2025 11:45:07 +0100", want},
+		{"5 Jan 2025 10:45:07 GMT", want},
+		{"  Sun, 5 Jan 2025 10:45:07 GMT\n", want},
+		{"Sunday, 5 Jan 2025 10:45:07 GMT", want},
+		{"Mon, 5 Jan 2025 10:45:07 GMT", want}, // wrong day of the week
+		{"Sun, 5 Jan 25 10:45:07 GMT", want},
+		{"Sun, 5 Jan 2025 10:45 GMT", want.Truncate(time.Minute)},
+		{"2025-01-05T10:45:07Z", want},
+		{"2025-01-05T11:45:07+01:00", want},
+		{"2025-01-05T10:45:07.250Z", want.Add(250 * time.Millisecond)},
+		{"2025-01-05T10:45:07", want},
+		{"2025-01-05", want.Truncate(24 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := parseDate(tt.in)
+		if err != nil {
+			t.Errorf("parseDate(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "yesterday", "Sun, 32 Jan 2025 10:45:07 GMT", "05/01/2025"} {
+		if got, err := parseDate(in); err == nil {
+			t.Errorf("parseDate(%q) = %v, want error", in, got)
+		}
+	}
+}
+
+func TestParseItemDates(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title><link>https://example.com/</link>
+<lastBuildDate>Sun, 5 Jan 2025 12:00:00 +0000</lastBuildDate>
+<item><title>Padded</title><link>https://example.com/1</link><pubDate>Sun, 05 Jan 2025 10:45:07 GMT</pubDate></item>
+<item><title>Single digit</title><link>https://example.com/2</link><pubDate>Sun, 5 Jan 2025 10:45:07 +0000</pubDate></item>
+<item><title>RFC 3339</title><link>https://example.com/3</link><pubDate>2025-01-05T10:45:07Z</pubDate></item>
+<item><title>Unparseable</title><link>https://example.com/4</link><pubDate>soon</pubDate></item>
+</channel></rss>`)
+	parsed, err := Parse(body, "https://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2025, time.January, 5, 10, 45, 7, 0, time.UTC)
+	if len(parsed.Items) != 3 {
+		t.Fatalf("parsed %d items, want 3; warnings: %q", len(parsed.Items), parsed.Warnings)
+	}
+	for _, item := range parsed.Items {
+		if !item.PublishedAt.Equal(want) {
+			t.Errorf("%s: published at %v, want %v", item.Title, item.PublishedAt, want)
+		}
+	}
+	if len(parsed.Warnings) != 1 {
+		t.Errorf("warnings = %q, want one for the unparseable date", parsed.Warnings)
+	}
+	if !parsed.LastBuildDate.Equal(want.Add(time.Hour + 14*time.Minute + 53*time.Second)) {
+		t.Errorf("last build date = %v", parsed.LastBuildDate)
+	}
+}
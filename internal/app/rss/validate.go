@@ -0,0 +1,71 @@
+package rss
+
+import (
+	"fmt"
+	"time"
+
+	models "rsshub/internal/domain"
+)
+
+// formatNames are the human-readable names of ParsedFeed formats.
+var formatNames = map[string]string{
+	"rss":   "RSS 2.0",
+	"atom":  "Atom",
+	"json":  "JSON Feed",
+	"route": "Scraper route",
+}
+
+// Report summarizes a parsed feed and the problems found in it, so that a
+// feed can be checked before subscribing to it.
+type Report struct {
+	Format   string
+	Title    string
+	Items    int
+	Oldest   time.Time
+	Newest   time.Time
+	Problems []string
+}
+
+// Validate checks a parsed feed for items that were dropped because their
+// date could not be parsed and for missing titles and links.
+func Validate(feed *models.ParsedFeed) Report {
+	r := Report{Format: feed.Format, Title: feed.Title, Items: len(feed.Items)}
+	if name, ok := formatNames[feed.Format]; ok {
+		r.Format = name
+	}
+	r.Problems = append(r.Problems, feed.Warnings...)
+	if feed.Title == "" {
+		r.Problems = append(r.Problems, "Feed has no title")
+	}
+	if len(feed.Items) == 0 {
+		r.Problems = append(r.Problems, "Feed has no items")
+	}
+
+	var noTitle, noLink int
+	for _, item := range feed.Items {
+		if item.Title == "" {
+			noTitle++
+		}
+		if item.Link == "" {
+			noLink++
+		}
+		if r.Oldest.IsZero() || item.PublishedAt.Before(r.Oldest) {
+			r.Oldest = item.PublishedAt
+		}
+		if item.PublishedAt.After(r.Newest) {
+			r.Newest = item.PublishedAt
+		}
+	}
+	if noTitle > 0 {
+		r.Problems = append(r.Problems, fmt.Sprintf("%d of %d items have no title", noTitle, len(feed.Items)))
+	}
+	if noLink > 0 {
+		r.Problems = append(r.Problems, fmt.Sprintf("%d of %d items have no link", noLink, len(feed.Items)))
+	}
+	return r
+}
+
+// OK reports whether no problems were found.
+func (r Report) OK() bool {
+	return len(r.Problems) == 0
+}
@@ -0,0 +1,385 @@
+package rule
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"rsshub/internal/domain"
+)
+
+// Query is a compiled rule query.
+//
+// A query is made of terms combined with AND, OR and NOT and grouped with
+// parentheses; terms next to each other must all match. A term is a word,
+// a "quoted phrase" or a /regular expression/, optionally prefixed with the
+// field it must match: title:, description:, author: or category:. Terms
+// without a field match the title or the description. Words and phrases
+// match case-insensitively at word boundaries; regular expressions are used
+// as written, so (?i) makes them case-insensitive.
+//
+//	title:golang AND (description:/CVE-\d+/ OR category:security) NOT author:"Jane Doe"
+type Query struct {
+	root node
+}
+
+// Compile parses a query.
+func Compile(query string) (*Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+	return &Query{root: root}, nil
+}
+
+// Match reports whether an article matches the query.
+func (q *Query) Match(article *domain.Article) bool {
+	return q.root.match(article)
+}
+
+// Fields that terms can be restricted to.
+const (
+	fieldAny         = ""
+	fieldTitle       = "title"
+	fieldDescription = "description"
+	fieldAuthor      = "author"
+	fieldCategory    = "category"
+)
+
+var fields = map[string]bool{fieldTitle: true, fieldDescription: true, fieldAuthor: true, fieldCategory: true}
+
+type node interface {
+	match(*domain.Article) bool
+}
+
+type andNode []node
+
+func (n andNode) match(a *domain.Article) bool {
+	for _, child := range n {
+		if !child.match(a) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []node
+
+func (n orNode) match(a *domain.Article) bool {
+	for _, child := range n {
+		if child.match(a) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct{ node }
+
+func (n notNode) match(a *domain.Article) bool {
+	return !n.node.match(a)
+}
+
+// termNode matches a word, phrase or regular expression in a field.
+type termNode struct {
+	field string
+	text  string // lowercased word or phrase, if re is nil
+	re    *regexp.Regexp
+}
+
+func (n termNode) match(a *domain.Article) bool {
+	switch n.field {
+	case fieldTitle:
+		return n.matchText(a.Title)
+	case fieldDescription:
+		return n.matchText(articleText(a))
+	case fieldAuthor:
+		return n.matchAny(a.Authors)
+	case fieldCategory:
+		return n.matchAny(a.Categories)
+	}
+	return n.matchText(a.Title) || n.matchText(articleText(a))
+}
+
+func (n termNode) matchAny(values []string) bool {
+	for _, v := range values {
+		if n.matchText(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n termNode) matchText(s string) bool {
+	if n.re != nil {
+		return n.re.MatchString(s)
+	}
+	return containsWord(strings.ToLower(s), n.text)
+}
+
+// articleText is the text the description field matches: the plain text of
+// the article body, which covers the full content when the feed has it.
+func articleText(a *domain.Article) string {
+	if a.PlainText != "" {
+		return a.PlainText
+	}
+	return a.Description
+}
+
+// containsWord reports whether word occurs in s with no letter or digit
+// directly before or after it.
+func containsWord(s, word string) bool {
+	for i := 0; i <= len(s)-len(word); {
+		j := strings.Index(s[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(s) || !isWordRune(after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		i = start + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Token kinds.
+const (
+	tokTerm = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	kind  int
+	field string
+	text  string
+	regex bool
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	case tokNot:
+		return "NOT"
+	case tokOpen:
+		return "("
+	case tokClose:
+		return ")"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokOpen})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokClose})
+			i++
+		default:
+			t, n, err := lexTerm(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += n
+		}
+	}
+	return tokens, nil
+}
+
+// lexTerm reads a term, or an operator, at the start of s and returns it
+// with its length.
+func lexTerm(s string) (token, int, error) {
+	t := token{kind: tokTerm}
+	n := 0
+	if colon := strings.IndexByte(s, ':'); colon > 0 && fields[strings.ToLower(s[:colon])] {
+		t.field = strings.ToLower(s[:colon])
+		n = colon + 1
+	}
+	if n == len(s) {
+		return t, 0, fmt.Errorf("missing value after %s:", t.field)
+	}
+
+	switch s[n] {
+	case '"', '/':
+		text, size, err := lexQuoted(s[n:])
+		if err != nil {
+			return t, 0, err
+		}
+		t.text, t.regex = text, s[n] == '/'
+		return t, n + size, nil
+	}
+
+	end := n
+	for end < len(s) && !strings.ContainsRune(" \t\r\n()\"", rune(s[end])) {
+		end++
+	}
+	t.text = s[n:end]
+	if t.field == "" {
+		switch t.text {
+		case "AND":
+			t.kind = tokAnd
+		case "OR":
+			t.kind = tokOr
+		case "NOT":
+			t.kind = tokNot
+		}
+	}
+	if t.text == "" {
+		return t, 0, fmt.Errorf("missing value after %s:", t.field)
+	}
+	return t, end, nil
+}
+
+// lexQuoted reads a phrase or regular expression delimited by its first
+// character, in which a backslash escapes the delimiter.
+func lexQuoted(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == quote:
+			b.WriteByte(quote)
+			i++
+		case s[i] == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	if quote == '/' {
+		return "", 0, fmt.Errorf("unterminated regular expression /%s", b.String())
+	}
+	return "", 0, fmt.Errorf("unterminated phrase \"%s", b.String())
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return token{}, false
+}
+
+// or parses terms separated by OR.
+func (p *parser) or() (node, error) {
+	var nodes orNode
+	for {
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if t, ok := p.peek(); !ok || t.kind != tokOr {
+			break
+		}
+		p.pos++
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// and parses terms separated by AND or nothing.
+func (p *parser) and() (node, error) {
+	var nodes andNode
+	for {
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		t, ok := p.peek()
+		if !ok || t.kind == tokOr || t.kind == tokClose {
+			break
+		}
+		if t.kind == tokAnd {
+			p.pos++
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) not() (node, error) {
+	if t, ok := p.peek(); ok && t.kind == tokNot {
+		p.pos++
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	p.pos++
+	switch t.kind {
+	case tokOpen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != tokClose {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	case tokTerm:
+		if t.regex {
+			re, err := regexp.Compile(t.text)
+			if err != nil {
+				return nil, err
+			}
+			return termNode{field: t.field, re: re}, nil
+		}
+		return termNode{field: t.field, text: strings.ToLower(t.text)}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", t)
+}
@@ -0,0 +1,180 @@
+package rule
+
+import (
+	"strings"
+	"testing"
+
+	"rsshub/internal/domain"
+)
+
+func TestQueryMatch(t *testing.T) {
+	article := &domain.Article{
+		Title:       "Go 1.24 released with generic type aliases",
+		Description: "<p>The Go team fixed CVE-2025-1234 in net/http.</p>",
+		PlainText:   "The Go team fixed CVE-2025-1234 in net/http.",
+		Authors:     []string{"Jane Doe", "Release Bot"},
+		Categories:  []string{"Security", "Releases"},
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		// Words match case-insensitively at word boundaries.
+		{"go", true},
+		{"GO", true},
+		{"gopher", false},
+		{"release", false},
+		{"released", true},
+		{"1.24", true},
+
+		// Phrases.
+		{`"generic type aliases"`, true},
+		{`"type generic"`, false},
+		{`"GO TEAM"`, true},
+
+		// Regular expressions, case-sensitive unless (?i).
+		{`/CVE-\d{4}-\d+/`, true},
+		{`/cve-\d+/`, false},
+		{`/(?i)cve-\d+/`, true},
+		{`/^Go \d/`, true},
+
+		// Fields.
+		{"title:released", true},
+		{"title:CVE", false},
+		{"description:cve", true},
+		{"description:aliases", false},
+		{`author:"Jane Doe"`, true},
+		{"author:jane", true},
+		{"author:john", false},
+		{"category:security", true},
+		{"CATEGORY:security", true},
+		{"category:go", false},
+		{`title:/^Go/`, true},
+		{"unknown:go", false},
+
+		// Operators and precedence: NOT binds tightest, then AND, which
+		// is also implied between terms, then OR.
+		{"go AND cve", true},
+		{"go cve", true},
+		{"go AND rust", false},
+		{"go rust", false},
+		{"go OR rust", true},
+		{"rust OR python", false},
+		{"NOT rust", true},
+		{"NOT go", false},
+		{"NOT NOT go", true},
+		{"go NOT rust", true},
+		{"go NOT cve", false},
+		{"rust AND python OR go", true},
+		{"rust AND (python OR go)", false},
+		{"go OR rust AND python", true},
+		{"(go OR rust) AND python", false},
+		{"NOT (rust OR python) AND go", true},
+		{"NOT rust OR python", true},
+		{"NOT (go OR python)", false},
+		{"title:golang AND (description:/CVE-\\d+/ OR category:security) NOT author:\"Release Bot\"", false},
+		{"title:go AND (description:/CVE-\\d+/ OR category:security) NOT author:\"Release Bot\"", false},
+		{"title:go AND (description:/CVE-\\d+/ OR category:security) NOT author:\"Other Bot\"", true},
+
+		// Lowercase operators are words.
+		{"go and rust", false},
+		{"go or rust", false},
+	}
+	for _, tt := range tests {
+		q, err := Compile(tt.query)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(article); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryDescriptionFallback(t *testing.T) {
+	q, err := Compile("description:outage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Match(&domain.Article{Description: "Major outage in eu-west"}) {
+		t.Error("description is not matched when there is no plain text")
+	}
+}
+
+func TestQueryEscapes(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		want  bool
+	}{
+		{`"say \"hi\""`, `they say "hi" twice`, true},
+		{`/a\/b/`, "path a/b here", true},
+		{`/a\/b/`, "path a-b here", false},
+	}
+	for _, tt := range tests {
+		q, err := Compile(tt.query)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(&domain.Article{Title: tt.text}); got != tt.want {
+			t.Errorf("%q on %q matched %v, want %v", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"", "empty query"},
+		{"   ", "empty query"},
+		{"(go", "missing )"},
+		{"go)", "unexpected )"},
+		{"()", "unexpected )"},
+		{"go AND", "unexpected end of query"},
+		{"go OR", "unexpected end of query"},
+		{"NOT", "unexpected end of query"},
+		{"OR go", "unexpected OR"},
+		{"go AND OR rust", "unexpected OR"},
+		{"title:", "missing value after title:"},
+		{"title: go", "missing value after title:"},
+		{`"open phrase`, "unterminated phrase"},
+		{"/open regex", "unterminated regular expression"},
+		{"/a(b/", "error parsing regexp"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.query)
+		if err == nil {
+			t.Errorf("Compile(%q) succeeded, want error containing %q", tt.query, tt.err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Compile(%q) = %v, want error containing %q", tt.query, err, tt.err)
+		}
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		s, word string
+		want    bool
+	}{
+		{"go is fun", "go", true},
+		{"golang is fun", "go", false},
+		{"let's go", "go", true},
+		{"ago, go", "go", true},
+		{"über-go", "go", true},
+		{"gögo", "go", false},
+		{"go2", "go", false},
+		{"", "go", false},
+		{"c++ rocks", "c++", true},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.s, tt.word); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.s, tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Package rule evaluates alert rules on new articles and sends the matches
+// to the sinks of each rule.
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"rsshub/internal/adapters/db"
+	"rsshub/internal/app/webhook"
+	"rsshub/internal/domain"
+	"rsshub/pkg/logger"
+	"rsshub/pkg/mailer"
+	"rsshub/pkg/uuid"
+)
+
+// SinkStdout logs alerts in the log of the fetch process.
+const SinkStdout = "stdout"
+
+// CheckSink returns an error if s is not a sink: stdout, an http or https
+// webhook URL, or a mailto: address.
+func CheckSink(s string) error {
+	switch {
+	case s == SinkStdout:
+		return nil
+	case strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://"):
+		return nil
+	case strings.HasPrefix(s, "mailto:"):
+		if _, err := mail.ParseAddress(strings.TrimPrefix(s, "mailto:")); err != nil {
+			return fmt.Errorf("invalid email address in %s: %v", s, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown sink %s: use stdout, a webhook URL or mailto:address", s)
+}
+
+// Alert is the new articles of a feed that matched a rule.
+type Alert struct {
+	Rule     domain.Rule
+	Feed     domain.Feed
+	Articles []domain.Article
+}
+
+// Engine evaluates the rules of a feed on the articles every fetch adds to
+// it, records the matches and sends alerts to the sinks of the rules in the
+// background.
+type Engine struct {
+	db     *db.DB
+	mailer *mailer.Mailer
+	client *http.Client
+	queue  chan Alert
+	log    *slog.Logger
+
+	// pending counts the alerts queued and not sent yet, for Flush.
+	pending sync.WaitGroup
+
+	// queries caches compiled queries by their text, which rules are
+	// evaluated with on every fetch.
+	queriesMu sync.Mutex
+	queries   map[string]*Query
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewEngine(db *db.DB, mailer *mailer.Mailer) *Engine {
+	return &Engine{
+		db:      db,
+		mailer:  mailer,
+		client:  &http.Client{Timeout: 15 * time.Second},
+		queue:   make(chan Alert, 256),
+		log:     logger.With("component", "rules"),
+		queries: make(map[string]*Query),
+	}
+}
+
+func (e *Engine) Start(parentCtx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ctx != nil {
+		return fmt.Errorf("already started")
+	}
+	e.ctx, e.cancel = context.WithCancel(parentCtx)
+	e.wg.Add(1)
+	go e.sender()
+	return nil
+}
+
+// Stop stops sending alerts. Alerts still queued are dropped; their
+// matches have already been recorded.
+func (e *Engine) Stop() error {
+	e.mu.Lock()
+	if e.ctx == nil {
+		e.mu.Unlock()
+		return fmt.Errorf("not started")
+	}
+	e.cancel()
+	e.mu.Unlock()
+	e.wg.Wait()
+	e.mu.Lock()
+	e.ctx = nil
+	e.mu.Unlock()
+	return nil
+}
+
+// Flush waits until the alerts queued so far have been sent, for processes
+// such as rsshub ingest that stop right after ingesting. It must not be
+// called while articles are still being checked, nor after Stop.
+func (e *Engine) Flush() {
+	e.pending.Wait()
+}
+
+// Check evaluates the rules of a feed on the articles a fetch inserted. It
+// is meant to be registered as an ingest hook, which runs after the
+// articles have been committed.
+func (e *Engine) Check(feed domain.Feed, result *domain.IngestResult) {
+	if len(result.Inserted) == 0 {
+		return
+	}
+	e.mu.Lock()
+	ctx := e.ctx
+	e.mu.Unlock()
+	if ctx == nil {
+		return
+	}
+
+	rules, err := e.db.GetFeedRules(ctx, feed)
+	if err != nil {
+		e.log.Error("Error getting rules of feed", "feed", feed.Name, "feed_id", feed.ID, "error", err)
+		return
+	}
+	for _, r := range rules {
+		query, err := e.compile(r.Query)
+		if err != nil {
+			e.log.Error("Error in rule", "rule", r.Name, "error", err)
+			continue
+		}
+		matched := make(map[string]domain.Article)
+		var ids []string
+		for _, article := range result.Inserted {
+			if query.Match(&article) {
+				matched[article.ID] = article
+				ids = append(ids, article.ID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		recorded, err := e.db.RecordMatches(ctx, r.ID, ids)
+		if err != nil {
+			e.log.Error("Error recording matches of rule", "rule", r.Name, "feed_id", feed.ID, "error", err)
+			continue
+		}
+		alert := Alert{Rule: r, Feed: feed}
+		for _, id := range recorded {
+			alert.Articles = append(alert.Articles, matched[id])
+		}
+		if len(alert.Articles) > 0 {
+			e.log.Debug("Rule matched", "rule", r.Name, "feed", feed.Name, "articles", len(alert.Articles))
+			e.enqueue(alert)
+		}
+	}
+}
+
+// enqueue queues an alert, dropping it if the queue is full so that ingest
+// hooks never wait for slow sinks.
+func (e *Engine) enqueue(alert Alert) {
+	e.pending.Add(1)
+	select {
+	case e.queue <- alert:
+	default:
+		e.pending.Done()
+		e.log.Warn("Alert queue full, dropping alert", "rule", alert.Rule.Name, "feed", alert.Feed.Name)
+	}
+}
+
+func (e *Engine) sender() {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case alert := <-e.queue:
+			for _, sink := range alert.Rule.Sinks {
+				if err := e.send(e.ctx, sink, alert); err != nil {
+					e.log.Error("Error sending alert", "rule", alert.Rule.Name, "sink", sink, "error", err)
+				}
+			}
+			e.pending.Done()
+		}
+	}
+}
+
+// compile returns the compiled query, compiling each query text once.
+func (e *Engine) compile(query string) (*Query, error) {
+	e.queriesMu.Lock()
+	defer e.queriesMu.Unlock()
+	if q, ok := e.queries[query]; ok {
+		return q, nil
+	}
+	q, err := Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	e.queries[query] = q
+	return q, nil
+}
+
+// send sends an alert to one sink.
+func (e *Engine) send(ctx context.Context, sink string, alert Alert) error {
+	switch {
+	case sink == SinkStdout:
+		for _, a := range alert.Articles {
+			e.log.Info("Rule matched", "rule", alert.Rule.Name, "feed", alert.Feed.Name, "feed_id", alert.Feed.ID,
+				"title", a.Title, "link", a.Link)
+		}
+		return nil
+
+	case strings.HasPrefix(sink, "mailto:"):
+		return e.mailer.Send(mailer.Message{
+			To:      []string{strings.TrimPrefix(sink, "mailto:")},
+			Subject: subject(alert),
+			Text:    text(alert),
+		})
+
+	default:
+		body, err := json.Marshal(webhook.Event{
+			Type:     webhook.EventRuleMatched,
+			Rule:     &webhook.EventRule{Name: alert.Rule.Name, Query: alert.Rule.Query},
+			Feed:     webhook.NewEventFeed(alert.Feed),
+			Articles: webhook.NewEventArticles(alert.Feed, alert.Articles),
+		})
+		if err != nil {
+			return err
+		}
+		id, err := uuid.New()
+		if err != nil {
+			return err
+		}
+		_, err = webhook.Post(ctx, e.client, sink, alert.Rule.Secret, webhook.EventRuleMatched, id, body)
+		return err
+	}
+}
+
+func subject(alert Alert) string {
+	if len(alert.Articles) == 1 {
+		return fmt.Sprintf("[%s] %s", alert.Rule.Name, alert.Articles[0].Title)
+	}
+	return fmt.Sprintf("[%s] %d new articles in %s", alert.Rule.Name, len(alert.Articles), alert.Feed.Name)
+}
+
+func text(alert Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "New articles in %s matched the rule %s:\n  %s\n", alert.Feed.Name, alert.Rule.Name, alert.Rule.Query)
+	for _, a := range alert.Articles {
+		fmt.Fprintf(&b, "\n%s\n%s\n", a.Title, a.Link)
+		if !a.PublishedAt.IsZero() {
+			fmt.Fprintf(&b, "Published %s\n", a.PublishedAt.Format("2006-01-02 15:04"))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"rsshub/internal/app/rss"
+	"rsshub/internal/domain"
+	"rsshub/pkg/charset"
+)
+
+// execTimeout bounds how long a feed command may run.
+const execTimeout = time.Minute
+
+// Exec runs the command of an exec:// URL and parses what it prints on
+// stdout as a feed, e.g. exec://git-log-feed --repo /srv/app. The command
+// and its arguments follow the scheme, separated by spaces (or %20).
+type Exec struct{}
+
+func (Exec) Fetch(ctx context.Context, feedURL string) (*domain.ParsedFeed, error) {
+	command, ok := strings.CutPrefix(feedURL, "exec://")
+	if !ok {
+		return nil, fmt.Errorf("not an exec:// URL: %s", feedURL)
+	}
+	command = strings.ReplaceAll(command, "%20", " ")
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing command in %s", feedURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	body, err := charset.ToUTF8(stdout.Bytes(), "")
+	if err != nil {
+		return nil, err
+	}
+	return rss.Parse(body, "")
+}
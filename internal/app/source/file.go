@@ -0,0 +1,37 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"rsshub/internal/app/rss"
+	"rsshub/internal/domain"
+	"rsshub/pkg/charset"
+)
+
+// File reads RSS, Atom and JSON feeds from local files given as file://
+// URLs, e.g. file:///var/lib/feeds/archive.xml.
+type File struct{}
+
+func (File) Fetch(ctx context.Context, feedURL string) (*domain.ParsedFeed, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "file" || u.Path == "" {
+		return nil, fmt.Errorf("not a file:// URL: %s", feedURL)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("file:// URL %s must be local", feedURL)
+	}
+	body, err := os.ReadFile(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	if body, err = charset.ToUTF8(body, ""); err != nil {
+		return nil, err
+	}
+	return rss.Parse(body, "")
+}
@@ -0,0 +1,15 @@
+package source
+
+import (
+	"context"
+
+	"rsshub/internal/app/rss"
+	"rsshub/internal/domain"
+)
+
+// HTTP fetches RSS, Atom and JSON feeds from the web.
+type HTTP struct{}
+
+func (HTTP) Fetch(ctx context.Context, feedURL string) (*domain.ParsedFeed, error) {
+	return rss.FetchAndParse(ctx, feedURL)
+}
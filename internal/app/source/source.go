@@ -0,0 +1,71 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"rsshub/internal/domain"
+)
+
+// Source fetches a feed from somewhere and normalizes it. Items must be
+// returned as the parsers do: with their GUID, link and dates set, and
+// without IDs.
+type Source interface {
+	Fetch(ctx context.Context, feedURL string) (*domain.ParsedFeed, error)
+}
+
+// Registry picks the source of a feed by the scheme of its URL.
+type Registry struct {
+	mu      sync.RWMutex
+	sources map[string]Source
+}
+
+func NewRegistry() *Registry {
+	return &Registry{sources: make(map[string]Source)}
+}
+
+// Register makes s the source of feeds whose URL has the scheme, replacing
+// any source registered for it before.
+func (r *Registry) Register(scheme string, s Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources[strings.ToLower(scheme)] = s
+}
+
+// Lookup returns the source for the feed URL, or false if its scheme has
+// none.
+func (r *Registry) Lookup(feedURL string) (Source, bool) {
+	// Not url.Parse: exec:// URLs hold a command line, which is no valid URL.
+	scheme, _, ok := strings.Cut(feedURL, "://")
+	if !ok {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sources[strings.ToLower(scheme)]
+	return s, ok
+}
+
+// Fetch fetches the feed from the source registered for its scheme.
+func (r *Registry) Fetch(ctx context.Context, feedURL string) (*domain.ParsedFeed, error) {
+	s, ok := r.Lookup(feedURL)
+	if !ok {
+		return nil, fmt.Errorf("unsupported feed URL %s (supported schemes: %s)", feedURL, strings.Join(r.Schemes(), ", "))
+	}
+	return s.Fetch(ctx, feedURL)
+}
+
+// Schemes returns the registered schemes in alphabetical order.
+func (r *Registry) Schemes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	schemes := make([]string, 0, len(r.sources))
+	for scheme := range r.sources {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
@@ -0,0 +1,370 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"rsshub/internal/adapters/db"
+	"rsshub/internal/domain"
+	"rsshub/pkg/logger"
+	"rsshub/pkg/uuid"
+)
+
+// Event types.
+const (
+	// EventArticlesCreated is sent with the articles a fetch added to a feed.
+	EventArticlesCreated = "articles.created"
+	// EventRuleMatched is sent by alert rules with the new articles that
+	// matched them.
+	EventRuleMatched = "rule.matched"
+)
+
+const (
+	// workers is the number of deliveries made at the same time.
+	workers = 4
+	// maxAttempts is the number of times a delivery is tried.
+	maxAttempts = 6
+	// firstRetry is the delay before the first retry; it doubles with
+	// every further one, so that the last is made about 5 minutes later.
+	firstRetry = 10 * time.Second
+	// pollInterval is how often workers look for retries that became due.
+	pollInterval = 5 * time.Second
+)
+
+// Event is the JSON payload posted to webhooks.
+type Event struct {
+	Type     string         `json:"event"`
+	Rule     *EventRule     `json:"rule,omitempty"`
+	Feed     *EventFeed     `json:"feed,omitempty"`
+	Articles []EventArticle `json:"articles"`
+}
+
+// EventRule describes the alert rule that the articles of an event matched.
+type EventRule struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// EventFeed describes the feed of the articles of an event.
+type EventFeed struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Title   string   `json:"title"`
+	SiteURL string   `json:"site_url"`
+	Tags    []string `json:"tags"`
+}
+
+// NewEventFeed returns the description of feed sent with events.
+func NewEventFeed(feed domain.Feed) *EventFeed {
+	return &EventFeed{Name: feed.Name, URL: feed.URL, Title: feed.Title, SiteURL: feed.SiteURL, Tags: feed.Tags}
+}
+
+// EventArticle is an article of an event, as published by its feed.
+type EventArticle struct {
+	ID          string       `json:"id"`
+	Feed        string       `json:"feed"`
+	GUID        string       `json:"guid"`
+	Title       string       `json:"title"`
+	Link        string       `json:"link"`
+	PublishedAt time.Time    `json:"published_at"`
+	Description string       `json:"description"`
+	Content     string       `json:"content,omitempty"`
+	Authors     []string     `json:"authors"`
+	Categories  []string     `json:"categories"`
+	Media       []EventMedia `json:"media,omitempty"`
+}
+
+// EventMedia is a file attached to an article of an event.
+type EventMedia struct {
+	URL          string `json:"url"`
+	MIMEType     string `json:"mime_type,omitempty"`
+	Length       int64  `json:"length,omitempty"`   // bytes
+	Duration     int    `json:"duration,omitempty"` // seconds
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// NewEventArticles returns the articles of feed as sent with events.
+func NewEventArticles(feed domain.Feed, articles []domain.Article) []EventArticle {
+	events := make([]EventArticle, 0, len(articles))
+	for _, a := range articles {
+		e := EventArticle{
+			ID:          a.ID,
+			Feed:        feed.Name,
+			GUID:        a.GUID,
+			Title:       a.Title,
+			Link:        a.Link,
+			PublishedAt: a.PublishedAt,
+			Description: a.Description,
+			Content:     a.Content,
+			Authors:     a.Authors,
+			Categories:  a.Categories,
+		}
+		if e.Authors == nil {
+			e.Authors = []string{}
+		}
+		if e.Categories == nil {
+			e.Categories = []string{}
+		}
+		for _, m := range a.Media {
+			e.Media = append(e.Media, EventMedia{URL: m.URL, MIMEType: m.MIMEType, Length: m.Length,
+				Duration: m.Duration, ThumbnailURL: m.ThumbnailURL})
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// Dispatcher delivers events to webhooks in the background, retrying failed
+// deliveries with exponential backoff. Deliveries are queued in the
+// database, where every attempt is logged, so that those pending when the
+// fetch process stops are made once it is started again.
+type Dispatcher struct {
+	db     *db.DB
+	client *http.Client
+	wake   chan struct{}
+	log    *slog.Logger
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(db *db.DB) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: 15 * time.Second},
+		wake:   make(chan struct{}, 1),
+		log:    logger.With("component", "webhooks"),
+	}
+}
+
+func (d *Dispatcher) Start(parentCtx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ctx != nil {
+		return fmt.Errorf("already started")
+	}
+	d.ctx, d.cancel = context.WithCancel(parentCtx)
+	if err := d.db.ResetDeliveries(d.ctx); err != nil {
+		d.log.Error("Error resuming webhook deliveries", "error", err)
+	}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	return nil
+}
+
+// StartQueueing starts the dispatcher without workers, so that events are
+// queued in the database for the fetch process to deliver. It is meant for
+// processes such as rsshub ingest that exit right after ingesting, which
+// must not reset or cut short the attempts of a running fetch process.
+func (d *Dispatcher) StartQueueing(parentCtx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ctx != nil {
+		return fmt.Errorf("already started")
+	}
+	d.ctx, d.cancel = context.WithCancel(parentCtx)
+	return nil
+}
+
+// Stop stops the workers. Deliveries that are queued or waiting for a
+// retry stay in the database; an attempt cut short is made again.
+func (d *Dispatcher) Stop() error {
+	d.mu.Lock()
+	if d.ctx == nil {
+		d.mu.Unlock()
+		return fmt.Errorf("not started")
+	}
+	d.cancel()
+	d.mu.Unlock()
+	d.wg.Wait()
+	d.mu.Lock()
+	d.ctx = nil
+	d.mu.Unlock()
+	return nil
+}
+
+// Notify sends the articles inserted by a fetch to the webhooks of the feed
+// and of its tags. It is meant to be registered as an ingest hook, which
+// runs after the articles have been committed.
+func (d *Dispatcher) Notify(feed domain.Feed, result *domain.IngestResult) {
+	if len(result.Inserted) == 0 {
+		return
+	}
+	d.mu.Lock()
+	ctx := d.ctx
+	d.mu.Unlock()
+	if ctx == nil {
+		return
+	}
+
+	hooks, err := d.db.GetFeedWebhooks(ctx, feed)
+	if err != nil {
+		d.log.Error("Error getting webhooks of feed", "feed", feed.Name, "feed_id", feed.ID, "error", err)
+		return
+	}
+	articles := NewEventArticles(feed, result.Inserted)
+	for _, hook := range hooks {
+		d.Send(ctx, hook, Event{Type: EventArticlesCreated, Feed: NewEventFeed(feed), Articles: articles})
+	}
+}
+
+// Send queues an event for delivery to a webhook.
+func (d *Dispatcher) Send(ctx context.Context, hook domain.Webhook, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.log.Error("Error encoding webhook event", "event", event.Type, "error", err)
+		return
+	}
+	id, err := uuid.New()
+	if err != nil {
+		d.log.Error("Error creating webhook delivery", "error", err)
+		return
+	}
+	delivery := domain.WebhookDelivery{
+		WebhookID:  hook.ID,
+		DeliveryID: id,
+		Event:      event.Type,
+		Attempt:    1,
+		Articles:   len(event.Articles),
+	}
+	if err := d.db.QueueDelivery(ctx, delivery, body); err != nil {
+		d.log.Error("Error queueing webhook delivery, dropping it", "delivery", id, "webhook_id", hook.ID, "url", hook.URL,
+			"event", event.Type, "error", err)
+		return
+	}
+	d.poke()
+}
+
+// poke wakes up a worker to look for due deliveries.
+func (d *Dispatcher) poke() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// worker makes the attempts that are due, and otherwise waits to be poked
+// or for pollInterval, after which retries may have become due.
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		job, err := d.db.ClaimDelivery(d.ctx)
+		if err == nil {
+			// There may be more, which another worker can make meanwhile.
+			d.poke()
+			d.deliver(job)
+			continue
+		}
+		if err != sql.ErrNoRows && d.ctx.Err() == nil {
+			d.log.Error("Error getting queued webhook deliveries", "error", err)
+		}
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver makes one attempt at a delivery, logs it and queues a retry if
+// it failed in a way that may be temporary.
+func (d *Dispatcher) deliver(job *domain.QueuedDelivery) {
+	start := time.Now()
+	status, err := Post(d.ctx, d.client, job.Webhook.URL, job.Webhook.Secret, job.Event, job.DeliveryID, job.Payload)
+	if d.ctx.Err() != nil {
+		// Stopped meanwhile; the attempt is made again on the next start.
+		return
+	}
+	entry := job.WebhookDelivery
+	entry.StatusCode = status
+	entry.Duration = time.Since(start)
+	log := d.log.With("delivery", job.DeliveryID, "webhook_id", job.WebhookID, "url", job.Webhook.URL, "event", job.Event,
+		"attempt", job.Attempt)
+
+	var delay time.Duration
+	switch {
+	case err == nil:
+		entry.State = domain.DeliveryDelivered
+		log.Debug("Webhook delivered", "status", status, "duration", entry.Duration)
+	case retryable(status) && job.Attempt < maxAttempts:
+		entry.State, entry.Error = domain.DeliveryRetrying, err.Error()
+		delay = firstRetry << (job.Attempt - 1)
+		log.Warn("Webhook delivery failed, will retry", "status", status, "retry_in", delay, "error", err)
+	default:
+		entry.State, entry.Error = domain.DeliveryFailed, err.Error()
+		log.Error("Giving up webhook delivery", "status", status, "error", err)
+	}
+	if err := d.db.FinishDelivery(d.ctx, entry, delay); err != nil {
+		log.Error("Error logging webhook delivery", "error", err)
+	}
+}
+
+// retryable reports whether a delivery that got the HTTP status code, or
+// none if it is 0, may succeed later. Client errors other than timeouts
+// and rate limiting will not.
+func retryable(status int) bool {
+	return status == 0 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// Post posts a JSON payload to url, signed with secret if it is set, and
+// returns the status code of the response, or 0 if there is none. Any
+// status other than 2xx is an error.
+func Post(ctx context.Context, client *http.Client, url, secret, event, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rsshub-webhook")
+	req.Header.Set("X-Rsshub-Event", event)
+	req.Header.Set("X-Rsshub-Delivery", deliveryID)
+	if secret != "" {
+		req.Header.Set("X-Rsshub-Signature-256", "sha256="+Sign(secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random secret for signing payloads.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"rsshub/internal/domain"
+)
+
+func TestNewEventArticles(t *testing.T) {
+	feed := domain.Feed{ID: "feed-id", Name: "lwn"}
+	published := time.Date(2025, time.January, 20, 15, 0, 0, 0, time.UTC)
+	articles := []domain.Article{{
+		ID:          "a1",
+		CreatedAt:   time.Now(),
+		GUID:        "https://lwn.net/Articles/1/",
+		Title:       "Kernel release",
+		Link:        "https://lwn.net/Articles/1/",
+		PublishedAt: published,
+		Description: "<p>Released.</p>",
+		PlainText:   "Released.",
+		ContentHash: "abc",
+		SimHash:     42,
+		ClusterID:   "a0",
+		FeedID:      "feed-id",
+		Media:       []domain.Media{{ID: "m1", ArticleID: "a1", URL: "https://lwn.net/a.mp3", MIMEType: "audio/mpeg"}},
+	}}
+	body, err := json.Marshal(Event{Type: EventArticlesCreated, Feed: NewEventFeed(feed),
+		Articles: NewEventArticles(feed, articles)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event struct {
+		Articles []map[string]any `json:"articles"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if len(event.Articles) != 1 {
+		t.Fatalf("got %d articles, want 1", len(event.Articles))
+	}
+	want := map[string]any{
+		"id":           "a1",
+		"feed":         "lwn",
+		"guid":         "https://lwn.net/Articles/1/",
+		"title":        "Kernel release",
+		"link":         "https://lwn.net/Articles/1/",
+		"published_at": "2025-01-20T15:00:00Z",
+		"description":  "<p>Released.</p>",
+		"authors":      []any{},
+		"categories":   []any{},
+		"media":        []any{map[string]any{"url": "https://lwn.net/a.mp3", "mime_type": "audio/mpeg"}},
+	}
+	if got := event.Articles[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("article = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,453 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rsshub/internal/app/rss"
+	"rsshub/internal/domain"
+	"rsshub/pkg/charset"
+	"rsshub/pkg/logger"
+)
+
+// CallbackPath is the path under which hubs call back, followed by the ID
+// of the feed.
+const CallbackPath = "/websub/"
+
+const (
+	// requestedLease is the lease asked for; hubs may grant another one.
+	requestedLease = 10 * 24 * time.Hour
+	// renewBefore is how long before they expire subscriptions are renewed.
+	renewBefore = 24 * time.Hour
+	// renewCheck is how often expiring subscriptions are looked for.
+	renewCheck = 10 * time.Minute
+	// pendingTimeout is how long a hub may take to verify a subscription
+	// before it is requested again.
+	pendingTimeout = time.Hour
+	// retryAfter is how long after a hub denied a subscription, or could
+	// not be reached, it is asked again.
+	retryAfter = 24 * time.Hour
+	// maxLease caps the lease granted by a hub, so that subscriptions are
+	// renewed at least this often.
+	maxLease = 30 * 24 * time.Hour
+	// maxPushSize limits the size of pushed content.
+	maxPushSize = 5 << 20
+)
+
+var feedIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Ingester stores pushed feeds; it is implemented by the aggregator.
+type Ingester interface {
+	Ingest(ctx context.Context, feed domain.Feed, parsed *domain.ParsedFeed) (*domain.IngestResult, error)
+}
+
+// Store keeps subscriptions; it is implemented by the database.
+type Store interface {
+	GetFeedByID(ctx context.Context, id string) (*domain.Feed, error)
+	GetSubscription(ctx context.Context, feedID string) (*domain.Subscription, error)
+	SaveSubscription(ctx context.Context, s domain.Subscription) error
+	SetSubscriptionRequested(ctx context.Context, feedID string) error
+	SetSubscriptionState(ctx context.Context, feedID, state string, lease time.Duration) error
+	GetExpiringSubscriptions(ctx context.Context, within time.Duration) ([]domain.Subscription, error)
+}
+
+// Manager subscribes feeds that advertise a WebSub hub to it and receives
+// their content when the hub pushes it. Feeds stay polled, less often, for
+// as long as their subscription is active, and as usual once it is not.
+type Manager struct {
+	db        Store
+	publicURL string
+	ingester  Ingester
+	client    *http.Client
+	log       *slog.Logger
+
+	mu       sync.Mutex
+	inFlight map[string]bool // feeds with a subscription request under way
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewManager returns a manager whose callbacks are served under publicURL,
+// the externally reachable URL of the HTTP server.
+func NewManager(db Store, publicURL string, ingester Ingester) *Manager {
+	return &Manager{
+		db:        db,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		ingester:  ingester,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		inFlight:  make(map[string]bool),
+		log:       logger.With("component", "websub"),
+	}
+}
+
+// Handler returns the HTTP handler of the callbacks, to be served at
+// CallbackPath.
+func (m *Manager) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+CallbackPath+"{feed}", m.verify)
+	mux.HandleFunc("POST "+CallbackPath+"{feed}", m.receive)
+	return mux
+}
+
+// Start starts renewing subscriptions before they expire.
+func (m *Manager) Start(parentCtx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx != nil {
+		return fmt.Errorf("already started")
+	}
+	m.ctx, m.cancel = context.WithCancel(parentCtx)
+	m.wg.Add(1)
+	go m.renewLoop()
+	return nil
+}
+
+// Stop stops renewing subscriptions and waits for pending requests to hubs.
+func (m *Manager) Stop() error {
+	m.mu.Lock()
+	if m.ctx == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("not started")
+	}
+	m.cancel()
+	m.mu.Unlock()
+	m.wg.Wait()
+	m.mu.Lock()
+	m.ctx = nil
+	m.mu.Unlock()
+	return nil
+}
+
+// Check subscribes a feed that has just been fetched to its hub, unless it
+// has no hub or is already subscribed. Requests to hubs are made in the
+// background.
+func (m *Manager) Check(feed domain.Feed) {
+	topic := feed.SelfURL
+	if !isHTTP(topic) {
+		topic = feed.URL
+	}
+	if feed.HubURL == "" || !isHTTP(feed.HubURL) || !isHTTP(topic) {
+		return
+	}
+
+	m.mu.Lock()
+	ctx := m.ctx
+	if ctx == nil || m.inFlight[feed.ID] {
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	sub, err := m.db.GetSubscription(ctx, feed.ID)
+	if err != nil && err != sql.ErrNoRows {
+		m.log.Error("Error getting subscription of feed", "feed", feed.Name, "feed_id", feed.ID, "error", err)
+		return
+	}
+	if err == nil && !needsSubscription(sub, feed.HubURL, topic) {
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		m.log.Error("Error subscribing feed", "feed", feed.Name, "feed_id", feed.ID, "error", err)
+		return
+	}
+	sub = &domain.Subscription{FeedID: feed.ID, Hub: feed.HubURL, Topic: topic, Secret: secret, State: domain.SubscriptionPending}
+	m.request(ctx, *sub, true)
+}
+
+// needsSubscription reports whether a feed with the subscription sub has
+// to be subscribed to hub for topic.
+func needsSubscription(sub *domain.Subscription, hub, topic string) bool {
+	if sub.Hub != hub || sub.Topic != topic {
+		return true
+	}
+	switch sub.State {
+	case domain.SubscriptionActive:
+		return !sub.ExpiresAt.After(time.Now())
+	case domain.SubscriptionPending:
+		return time.Since(sub.UpdatedAt) > pendingTimeout
+	default:
+		return time.Since(sub.UpdatedAt) > retryAfter
+	}
+}
+
+func (m *Manager) renewLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(renewCheck)
+	defer ticker.Stop()
+	for {
+		m.renew()
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renew renews the subscriptions that expire soon, keeping their secret so
+// that content pushed meanwhile can still be verified.
+func (m *Manager) renew() {
+	subs, err := m.db.GetExpiringSubscriptions(m.ctx, renewBefore)
+	if err != nil {
+		m.log.Error("Error getting expiring subscriptions", "error", err)
+		return
+	}
+	for _, sub := range subs {
+		m.request(m.ctx, sub, false)
+	}
+}
+
+// request asks the hub, in the background, to subscribe the callback of
+// the feed to the topic, unless a request for the feed is already under
+// way. New subscriptions are saved as pending first.
+func (m *Manager) request(ctx context.Context, sub domain.Subscription, isNew bool) {
+	m.mu.Lock()
+	if m.inFlight[sub.FeedID] {
+		m.mu.Unlock()
+		return
+	}
+	m.inFlight[sub.FeedID] = true
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			m.mu.Lock()
+			delete(m.inFlight, sub.FeedID)
+			m.mu.Unlock()
+		}()
+
+		if isNew {
+			if err := m.db.SaveSubscription(ctx, sub); err != nil {
+				m.log.Error("Error saving subscription", "feed_id", sub.FeedID, "hub", sub.Hub, "error", err)
+				return
+			}
+		} else if err := m.db.SetSubscriptionRequested(ctx, sub.FeedID); err != nil {
+			m.log.Error("Error saving subscription", "feed_id", sub.FeedID, "hub", sub.Hub, "error", err)
+			return
+		}
+		if err := m.subscribe(ctx, sub); err != nil {
+			m.log.Error("Error subscribing", "feed_id", sub.FeedID, "url", sub.Topic, "hub", sub.Hub, "error", err)
+			if isNew {
+				if err := m.db.SetSubscriptionState(ctx, sub.FeedID, domain.SubscriptionFailed, 0); err != nil {
+					m.log.Error("Error saving subscription state", "feed_id", sub.FeedID, "error", err)
+				}
+			}
+			return
+		}
+		m.log.Info("Requested subscription", "feed_id", sub.FeedID, "url", sub.Topic, "hub", sub.Hub)
+	}()
+}
+
+func (m *Manager) subscribe(ctx context.Context, sub domain.Subscription) error {
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {sub.Topic},
+		"hub.callback":      {m.publicURL + CallbackPath + sub.FeedID},
+		"hub.secret":        {sub.Secret},
+		"hub.lease_seconds": {strconv.Itoa(int(requestedLease.Seconds()))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// verify answers the hub's verification of intent: it confirms pending and
+// renewed subscriptions by echoing the challenge, and records denials.
+// Verifications of subscriptions that were not requested are refused, so
+// that nobody else can activate one and stop the feed from being polled.
+func (m *Manager) verify(w http.ResponseWriter, r *http.Request) {
+	sub, ok := m.subscription(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	if q.Get("hub.topic") != sub.Topic || !awaitingVerification(sub) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch q.Get("hub.mode") {
+	case "subscribe":
+		challenge := q.Get("hub.challenge")
+		if challenge == "" {
+			http.Error(w, "missing hub.challenge", http.StatusBadRequest)
+			return
+		}
+		lease := requestedLease
+		if seconds, err := strconv.ParseInt(q.Get("hub.lease_seconds"), 10, 64); err == nil && seconds > 0 {
+			lease = time.Duration(min(seconds, int64(maxLease/time.Second))) * time.Second
+		}
+		if err := m.db.SetSubscriptionState(r.Context(), sub.FeedID, domain.SubscriptionActive, lease); err != nil {
+			m.log.Error("Error saving subscription state", "feed_id", sub.FeedID, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		m.log.Info("Subscribed", "feed_id", sub.FeedID, "url", sub.Topic, "hub", sub.Hub, "lease", lease)
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, challenge)
+	case "denied":
+		if err := m.db.SetSubscriptionState(r.Context(), sub.FeedID, domain.SubscriptionDenied, 0); err != nil {
+			m.log.Error("Error saving subscription state", "feed_id", sub.FeedID, "error", err)
+		}
+		m.log.Warn("Subscription denied", "feed_id", sub.FeedID, "url", sub.Topic, "hub", sub.Hub, "reason", q.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+	default:
+		// Subscriptions are never cancelled from here, so an unsubscribe
+		// request was not made by us.
+		http.NotFound(w, r)
+	}
+}
+
+// receive ingests content pushed by the hub. Content with a missing or
+// wrong signature is acknowledged but dropped, as the protocol requires.
+func (m *Manager) receive(w http.ResponseWriter, r *http.Request) {
+	sub, ok := m.subscription(w, r)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushSize+1))
+	if err != nil {
+		http.Error(w, "error reading body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxPushSize {
+		http.Error(w, "content too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !validSignature(r.Header.Get("X-Hub-Signature"), body, sub.Secret) {
+		m.log.Warn("Dropped pushed content with an invalid signature", "feed_id", sub.FeedID, "url", sub.Topic)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := m.db.GetFeedByID(r.Context(), sub.FeedID)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	body, err = charset.ToUTF8(body, r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parsed, err := rss.Parse(body, sub.Topic)
+	if err != nil {
+		m.log.Error("Error parsing pushed content", "feed", feed.Name, "feed_id", feed.ID, "error", err)
+		http.Error(w, "invalid feed", http.StatusBadRequest)
+		return
+	}
+	result, err := m.ingester.Ingest(r.Context(), *feed, parsed)
+	if err != nil {
+		m.log.Error("Error ingesting pushed content", "feed", feed.Name, "feed_id", feed.ID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	m.log.Info("Feed pushed", "feed", feed.Name, "feed_id", feed.ID, "new", len(result.Inserted),
+		"updated", len(result.Updated), "filtered", result.Filtered)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// awaitingVerification reports whether the hub was asked for the
+// subscription, new or renewed, recently enough to be verifying it now.
+func awaitingVerification(sub *domain.Subscription) bool {
+	if sub.State != domain.SubscriptionPending && sub.State != domain.SubscriptionActive {
+		return false
+	}
+	return !sub.RequestedAt.IsZero() && time.Since(sub.RequestedAt) < pendingTimeout
+}
+
+// subscription returns the subscription a callback is for. If there is
+// none it answers 410 Gone, which tells the hub to stop pushing.
+func (m *Manager) subscription(w http.ResponseWriter, r *http.Request) (*domain.Subscription, bool) {
+	feedID := r.PathValue("feed")
+	if !feedIDPattern.MatchString(feedID) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	sub, err := m.db.GetSubscription(r.Context(), feedID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "no such subscription", http.StatusGone)
+		return nil, false
+	}
+	if err != nil {
+		m.log.Error("Error getting subscription", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return sub, true
+}
+
+// validSignature checks an X-Hub-Signature header, "method=hexdigest", of
+// the body against the secret.
+func validSignature(header string, body []byte, secret string) bool {
+	method, digest, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+	var h func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+	want, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func isHTTP(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
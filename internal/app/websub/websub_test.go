@@ -0,0 +1,277 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"rsshub/internal/domain"
+)
+
+const testFeedID = "6c1e8a52-3b0f-4c4e-9d55-2f1b7c9a0e11"
+
+// fakeStore keeps one subscription in memory.
+type fakeStore struct {
+	sub   *domain.Subscription
+	lease time.Duration
+}
+
+func (s *fakeStore) GetFeedByID(_ context.Context, id string) (*domain.Feed, error) {
+	return &domain.Feed{ID: id, Name: "test"}, nil
+}
+
+func (s *fakeStore) GetSubscription(_ context.Context, feedID string) (*domain.Subscription, error) {
+	if s.sub == nil || s.sub.FeedID != feedID {
+		return nil, sql.ErrNoRows
+	}
+	sub := *s.sub
+	return &sub, nil
+}
+
+func (s *fakeStore) SaveSubscription(_ context.Context, sub domain.Subscription) error {
+	sub.RequestedAt = time.Now()
+	s.sub = &sub
+	return nil
+}
+
+func (s *fakeStore) SetSubscriptionRequested(_ context.Context, feedID string) error {
+	s.sub.RequestedAt = time.Now()
+	return nil
+}
+
+func (s *fakeStore) SetSubscriptionState(_ context.Context, feedID, state string, lease time.Duration) error {
+	s.sub.State = state
+	s.sub.RequestedAt = time.Time{}
+	s.lease = lease
+	return nil
+}
+
+func (s *fakeStore) GetExpiringSubscriptions(context.Context, time.Duration) ([]domain.Subscription, error) {
+	return nil, nil
+}
+
+// fakeIngester records the feeds pushed to it.
+type fakeIngester struct {
+	parsed []*domain.ParsedFeed
+}
+
+func (i *fakeIngester) Ingest(_ context.Context, _ domain.Feed, parsed *domain.ParsedFeed) (*domain.IngestResult, error) {
+	i.parsed = append(i.parsed, parsed)
+	return &domain.IngestResult{}, nil
+}
+
+func newTestManager(sub *domain.Subscription) (*Manager, *fakeStore, *fakeIngester) {
+	store := &fakeStore{sub: sub}
+	ingester := &fakeIngester{}
+	return NewManager(store, "https://rsshub.example.com", ingester), store, ingester
+}
+
+func pendingSubscription() *domain.Subscription {
+	return &domain.Subscription{
+		FeedID:      testFeedID,
+		Hub:         "https://hub.example.com/",
+		Topic:       "https://example.com/feed.xml",
+		Secret:      "s3cret",
+		State:       domain.SubscriptionPending,
+		RequestedAt: time.Now().Add(-time.Minute),
+	}
+}
+
+func verifyRequest(m *Manager, feedID string, params url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, CallbackPath+feedID+"?"+params.Encode(), nil)
+	m.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func subscribeParams(topic, lease string) url.Values {
+	params := url.Values{
+		"hub.mode":      {"subscribe"},
+		"hub.topic":     {topic},
+		"hub.challenge": {"c4a11e"},
+	}
+	if lease != "" {
+		params.Set("hub.lease_seconds", lease)
+	}
+	return params
+}
+
+func TestVerify(t *testing.T) {
+	const topic = "https://example.com/feed.xml"
+	renewing := pendingSubscription()
+	renewing.State = domain.SubscriptionActive
+	active := pendingSubscription()
+	active.State = domain.SubscriptionActive
+	active.RequestedAt = time.Time{}
+	stale := pendingSubscription()
+	stale.RequestedAt = time.Now().Add(-2 * pendingTimeout)
+	failed := pendingSubscription()
+	failed.State = domain.SubscriptionFailed
+
+	tests := []struct {
+		name      string
+		sub       *domain.Subscription
+		feedID    string
+		params    url.Values
+		wantCode  int
+		wantState string
+		wantLease time.Duration
+	}{
+		{"pending", pendingSubscription(), testFeedID, subscribeParams(topic, "86400"), http.StatusOK, domain.SubscriptionActive, 24 * time.Hour},
+		{"renewal", renewing, testFeedID, subscribeParams(topic, ""), http.StatusOK, domain.SubscriptionActive, requestedLease},
+		{"lease clamped", pendingSubscription(), testFeedID, subscribeParams(topic, "9223372036854775807"), http.StatusOK, domain.SubscriptionActive, maxLease},
+		{"lease not a number", pendingSubscription(), testFeedID, subscribeParams(topic, "soon"), http.StatusOK, domain.SubscriptionActive, requestedLease},
+		{"not requested", active, testFeedID, subscribeParams(topic, "86400"), http.StatusNotFound, domain.SubscriptionActive, 0},
+		{"request timed out", stale, testFeedID, subscribeParams(topic, "86400"), http.StatusNotFound, domain.SubscriptionPending, 0},
+		{"failed", failed, testFeedID, subscribeParams(topic, "86400"), http.StatusNotFound, domain.SubscriptionFailed, 0},
+		{"other topic", pendingSubscription(), testFeedID, subscribeParams("https://evil.example.com/", "86400"), http.StatusNotFound, domain.SubscriptionPending, 0},
+		{"no challenge", pendingSubscription(), testFeedID, url.Values{"hub.mode": {"subscribe"}, "hub.topic": {topic}}, http.StatusBadRequest, domain.SubscriptionPending, 0},
+		{"unsubscribe", pendingSubscription(), testFeedID, url.Values{"hub.mode": {"unsubscribe"}, "hub.topic": {topic}, "hub.challenge": {"x"}}, http.StatusNotFound, domain.SubscriptionPending, 0},
+		{"denied", pendingSubscription(), testFeedID, url.Values{"hub.mode": {"denied"}, "hub.topic": {topic}}, http.StatusOK, domain.SubscriptionDenied, 0},
+		{"unknown feed", pendingSubscription(), "00000000-0000-0000-0000-000000000000", subscribeParams(topic, ""), http.StatusGone, domain.SubscriptionPending, 0},
+		{"malformed feed ID", pendingSubscription(), "feed", subscribeParams(topic, ""), http.StatusNotFound, domain.SubscriptionPending, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, store, _ := newTestManager(tt.sub)
+			w := verifyRequest(m, tt.feedID, tt.params)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if store.sub.State != tt.wantState {
+				t.Errorf("state = %q, want %q", store.sub.State, tt.wantState)
+			}
+			if tt.wantState == domain.SubscriptionActive && tt.wantCode == http.StatusOK {
+				if body := w.Body.String(); body != "c4a11e" {
+					t.Errorf("body = %q, want the challenge", body)
+				}
+				if store.lease != tt.wantLease {
+					t.Errorf("lease = %v, want %v", store.lease, tt.wantLease)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyOnlyOnce(t *testing.T) {
+	m, _, _ := newTestManager(pendingSubscription())
+	params := subscribeParams("https://example.com/feed.xml", "")
+	if w := verifyRequest(m, testFeedID, params); w.Code != http.StatusOK {
+		t.Fatalf("first verification: status = %d", w.Code)
+	}
+	if w := verifyRequest(m, testFeedID, params); w.Code != http.StatusNotFound {
+		t.Fatalf("replayed verification: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Example</title><link>https://example.com/</link>
+<item><title>Pushed</title><link>https://example.com/pushed</link><guid>pushed-1</guid>
+<pubDate>Mon, 20 Jan 2025 10:45:07 GMT</pubDate></item>
+</channel></rss>`
+
+func sign(newHash func() hash.Hash, method, body, secret string) string {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(body))
+	return method + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestReceive(t *testing.T) {
+	tests := []struct {
+		name       string
+		feedID     string
+		body       string
+		signature  string
+		wantCode   int
+		wantIngest bool
+	}{
+		{"signed", testFeedID, testFeed, sign(sha256.New, "sha256", testFeed, "s3cret"), http.StatusNoContent, true},
+		{"signed with sha1", testFeedID, testFeed, sign(sha1.New, "sha1", testFeed, "s3cret"), http.StatusNoContent, true},
+		{"wrong secret", testFeedID, testFeed, sign(sha256.New, "sha256", testFeed, "guess"), http.StatusAccepted, false},
+		{"unsigned", testFeedID, testFeed, "", http.StatusAccepted, false},
+		{"not a feed", testFeedID, "hello", sign(sha256.New, "sha256", "hello", "s3cret"), http.StatusBadRequest, false},
+		{"too large", testFeedID, strings.Repeat("x", maxPushSize+1), "", http.StatusRequestEntityTooLarge, false},
+		{"unknown feed", "00000000-0000-0000-0000-000000000000", testFeed, sign(sha256.New, "sha256", testFeed, "s3cret"), http.StatusGone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := pendingSubscription()
+			sub.State = domain.SubscriptionActive
+			m, _, ingester := newTestManager(sub)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, CallbackPath+tt.feedID, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/rss+xml")
+			if tt.signature != "" {
+				r.Header.Set("X-Hub-Signature", tt.signature)
+			}
+			m.Handler().ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := len(ingester.parsed) == 1; got != tt.wantIngest {
+				t.Fatalf("ingested = %v, want %v", got, tt.wantIngest)
+			}
+			if tt.wantIngest && ingester.parsed[0].Items[0].Title != "Pushed" {
+				t.Errorf("ingested %+v", ingester.parsed[0].Items)
+			}
+		})
+	}
+}
+
+func TestValidSignature(t *testing.T) {
+	const body, secret = "payload", "s3cret"
+	valid := sign(sha256.New, "sha256", body, secret)
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{valid, true},
+		{strings.ToUpper(valid[:6]) + valid[6:], true},
+		{sign(sha1.New, "sha1", body, secret), true},
+		{sign(sha256.New, "sha256", body+"!", secret), false},
+		{sign(sha256.New, "sha256", body, "other"), false},
+		{sign(sha1.New, "sha256", body, secret), false},
+		{"md5=" + strings.Repeat("0", 32), false},
+		{"sha256=not-hex", false},
+		{"sha256", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validSignature(tt.header, []byte(body), secret); got != tt.want {
+			t.Errorf("validSignature(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRenewalMarksRequested(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	sub := pendingSubscription()
+	sub.Hub = hub.URL
+	sub.State = domain.SubscriptionActive
+	sub.RequestedAt = time.Time{}
+	m, store, _ := newTestManager(sub)
+	m.request(context.Background(), *sub, false)
+	m.wg.Wait()
+	if store.sub.RequestedAt.IsZero() {
+		t.Fatal("renewal was not marked as requested")
+	}
+	w := verifyRequest(m, testFeedID, subscribeParams(sub.Topic, fmt.Sprint(int(time.Hour/time.Second))))
+	if w.Code != http.StatusOK || store.lease != time.Hour {
+		t.Fatalf("status = %d, lease = %v", w.Code, store.lease)
+	}
+}
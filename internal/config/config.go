@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +16,46 @@ type Config struct {
 	PGPassword    string
 	PGDBName      string
 	PGSSLmode     string
+
+	// Full-text extraction has its own workers, polling interval and
+	// request rates, independent of feed polling.
+	ExtractWorkers   int
+	ExtractInterval  time.Duration
+	ExtractGap       time.Duration
+	ExtractHostDelay time.Duration
+
+	// RoutesDir holds the scraper rules of route:// feeds.
+	RoutesDir string
+	// ExecSources allows exec:// feeds, which run commands.
+	ExecSources bool
+
+	// HTTPAddr is the listen address of the HTTP server of the fetch
+	// process; it is not started if empty. PublicURL is where that server
+	// can be reached from outside, e.g. by WebSub hubs.
+	HTTPAddr  string
+	PublicURL string
+
+	// SMTP server that email alerts are sent through, as host:port, with
+	// the credentials and sender address to use. Email is disabled if
+	// SMTPAddr is empty.
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// LogLevel is the lowest level logged: debug, info, warn or error.
+	// LogFormat is pretty, text or json.
+	LogLevel  string
+	LogFormat string
+
+	// TraceExporter is where spans of feed fetches are sent: none, stdout,
+	// file or otlp. TraceFile is the file appended to by the file exporter;
+	// TraceEndpoint and TraceHeaders are the collector the otlp exporter
+	// posts to and the headers it adds, as key=value pairs.
+	TraceExporter string
+	TraceFile     string
+	TraceEndpoint string
+	TraceHeaders  string
 }
 
 func LoadConfig() (*Config, error) {
@@ -36,14 +77,57 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	extractWorkers, err := strconv.Atoi(getEnv("CLI_APP_EXTRACT_WORKERS", "2"))
+	if err != nil {
+		return nil, err
+	}
+	extractInterval, err := time.ParseDuration(getEnv("CLI_APP_EXTRACT_INTERVAL", "1m"))
+	if err != nil {
+		return nil, err
+	}
+	extractGap, err := time.ParseDuration(getEnv("CLI_APP_EXTRACT_GAP", "1s"))
+	if err != nil {
+		return nil, err
+	}
+	extractHostDelay, err := time.ParseDuration(getEnv("CLI_APP_EXTRACT_HOST_DELAY", "5s"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		TimerInterval: interval,
-		WorkersCount:  workers,
-		PGHost:        os.Getenv("POSTGRES_HOST"),
-		PGPort:        os.Getenv("POSTGRES_PORT"),
-		PGUser:        os.Getenv("POSTGRES_USER"),
-		PGPassword:    os.Getenv("POSTGRES_PASSWORD"),
-		PGDBName:      os.Getenv("POSTGRES_DBNAME"),
-		PGSSLmode:     "disable", // Default
+		TimerInterval:    interval,
+		WorkersCount:     workers,
+		PGHost:           os.Getenv("POSTGRES_HOST"),
+		PGPort:           os.Getenv("POSTGRES_PORT"),
+		PGUser:           os.Getenv("POSTGRES_USER"),
+		PGPassword:       os.Getenv("POSTGRES_PASSWORD"),
+		PGDBName:         os.Getenv("POSTGRES_DBNAME"),
+		PGSSLmode:        "disable", // Default
+		ExtractWorkers:   extractWorkers,
+		ExtractInterval:  extractInterval,
+		ExtractGap:       extractGap,
+		ExtractHostDelay: extractHostDelay,
+		RoutesDir:        getEnv("CLI_APP_ROUTES_DIR", "routes"),
+		ExecSources:      os.Getenv("CLI_APP_EXEC_SOURCES") == "true",
+		HTTPAddr:         os.Getenv("CLI_APP_HTTP_ADDR"),
+		PublicURL:        strings.TrimSuffix(os.Getenv("CLI_APP_PUBLIC_URL"), "/"),
+		SMTPAddr:         os.Getenv("CLI_APP_SMTP_ADDR"),
+		SMTPUsername:     os.Getenv("CLI_APP_SMTP_USERNAME"),
+		SMTPPassword:     os.Getenv("CLI_APP_SMTP_PASSWORD"),
+		SMTPFrom:         getEnv("CLI_APP_SMTP_FROM", "rsshub@localhost"),
+		LogLevel:         getEnv("CLI_APP_LOG_LEVEL", "info"),
+		LogFormat:        getEnv("CLI_APP_LOG_FORMAT", "pretty"),
+		TraceExporter:    getEnv("CLI_APP_TRACE_EXPORTER", "none"),
+		TraceFile:        getEnv("CLI_APP_TRACE_FILE", "traces.jsonl"),
+		TraceEndpoint:    getEnv("CLI_APP_TRACE_OTLP_ENDPOINT", getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")),
+		TraceHeaders:     os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"),
 	}, nil
 }
+
+// getEnv returns the environment variable, or def if it is unset or empty.
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -1,40 +1,462 @@
 package domain
 
 import (
+	"encoding/xml"
 	"time"
 )
 
 type Feed struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Name      string    `json:"name"`
-	URL       string    `json:"url"`
+	ID            string    `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Name          string    `json:"name"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title"`
+	SiteURL       string    `json:"site_url"`
+	Description   string    `json:"description"`
+	Language      string    `json:"language"`
+	IconURL       string    `json:"icon_url"`
+	LastBuildDate time.Time `json:"last_build_date"`
+	Generator     string    `json:"generator"`
+	TTL           int       `json:"ttl"` // minutes
+	FullText      bool      `json:"full_text"`
+	Tags          []string  `json:"tags"`
+	HubURL        string    `json:"hub_url"`        // WebSub hub advertised by the feed
+	SelfURL       string    `json:"self_url"`       // the feed's own URL, its WebSub topic
+	ParseStatus   string    `json:"parse_status"`   // one of the Parse* statuses, "" until fetched
+	ParseMessages []string  `json:"parse_messages"` // the warnings, or the error, of the last fetch
 }
 
+// Parse statuses of a feed, recording how its last fetch went.
+const (
+	ParseOK       = "ok"
+	ParseWarnings = "warnings" // parsed, but items were dropped or the document had to be repaired
+	ParseFailed   = "failed"
+)
+
 type Article struct {
 	ID          string    `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	GUID        string    `json:"guid"`
 	Title       string    `json:"title"`
 	Link        string    `json:"link"`
 	PublishedAt time.Time `json:"published_at"`
 	Description string    `json:"description"`
+	Content     string    `json:"content"`
+	PlainText   string    `json:"plain_text"`
+	FullContent string    `json:"full_content,omitempty"`
+	ExtractedAt time.Time `json:"extracted_at"`
+	Authors     []string  `json:"authors"`
+	Categories  []string  `json:"categories"`
+	ContentHash string    `json:"content_hash"`
+	SimHash     uint64    `json:"simhash"`
+	ClusterID   string    `json:"cluster_id"`
 	FeedID      string    `json:"feed_id"`
+	Media       []Media   `json:"media,omitempty"`
+}
+
+// Media is a file attached to an article: a podcast episode, a video or an
+// image, from an RSS enclosure, an Atom enclosure link or Media RSS.
+type Media struct {
+	ID           string `json:"id"`
+	ArticleID    string `json:"article_id"`
+	URL          string `json:"url"`
+	MIMEType     string `json:"mime_type"`
+	Length       int64  `json:"length"`   // bytes
+	Duration     int    `json:"duration"` // seconds
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// ArticleFilter selects articles for listing. Empty fields match anything.
+type ArticleFilter struct {
+	FeedName string
+	Author   string // case-insensitive substring of any author
+	Category string // case-insensitive match of any category
+	Limit    int
+}
+
+// IngestResult lists the articles created and changed by one fetch of a feed.
+type IngestResult struct {
+	Inserted []Article
+	Updated  []Article
+	Filtered int // items dropped by the feed's filters
+}
+
+// Filter is a step of the ingest pipeline of a feed, which every parsed
+// item goes through, in order of Position, before it is stored.
+type Filter struct {
+	ID          string
+	CreatedAt   time.Time
+	FeedID      string
+	Position    int
+	Action      string // one of the Filter* actions
+	Field       string // FilterTitle or FilterCategory, for include and exclude
+	Pattern     string // regular expression
+	Replacement string // for rewrite-title
+	MinLength   int    // for min-length, in characters
+}
+
+// Filter actions.
+const (
+	FilterInclude      = "include"       // drop items that do not match
+	FilterExclude      = "exclude"       // drop items that match
+	FilterMinLength    = "min-length"    // drop items with shorter text
+	FilterRewriteTitle = "rewrite-title" // replace matches in titles
+	FilterStripParams  = "strip-params"  // remove matching query parameters from links
+)
+
+// Fields that include and exclude filters match.
+const (
+	FilterTitle    = "title"
+	FilterCategory = "category"
+)
+
+// Subscription is a WebSub subscription of a feed to its hub, through which
+// the hub pushes new content instead of waiting to be polled.
+type Subscription struct {
+	FeedID    string
+	Hub       string
+	Topic     string
+	Secret    string // key of the HMAC signatures of pushed content
+	State     string // one of the Subscription* states
+	ExpiresAt time.Time
+	UpdatedAt time.Time
+	// RequestedAt is when the hub was last asked to subscribe, if it has
+	// not answered yet.
+	RequestedAt time.Time
+}
+
+// Subscription states. A subscription is pending until the hub verifies
+// it, and denied if the hub refuses it.
+const (
+	SubscriptionPending = "pending"
+	SubscriptionActive  = "active"
+	SubscriptionDenied  = "denied"
+	SubscriptionFailed  = "failed"
+)
+
+// Webhook posts events about the articles of one feed, or of every feed
+// with a tag, to a URL.
+type Webhook struct {
+	ID        string
+	CreatedAt time.Time
+	URL       string
+	Secret    string // key of the HMAC signature of payloads, if set
+	FeedID    string
+	FeedName  string
+	Tag       string
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook. Retries
+// of the same event share its DeliveryID.
+type WebhookDelivery struct {
+	ID         string
+	CreatedAt  time.Time
+	WebhookID  string
+	WebhookURL string
+	DeliveryID string
+	Event      string
+	Attempt    int
+	State      string    // one of the Delivery* states
+	AttemptAt  time.Time // when the attempt is due, or was made
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	Articles   int
+}
+
+// Webhook delivery states. An attempt is pending until it is made, and then
+// delivered, retrying if it failed and another attempt is queued, or failed
+// if it was the last one.
+const (
+	DeliveryPending   = "pending"
+	DeliverySending   = "sending"
+	DeliveryDelivered = "delivered"
+	DeliveryRetrying  = "retrying"
+	DeliveryFailed    = "failed"
+)
+
+// QueuedDelivery is a delivery attempt about to be made, with the webhook
+// and the payload to post to it.
+type QueuedDelivery struct {
+	WebhookDelivery
+	Webhook Webhook
+	Payload []byte
 }
 
+// Rule raises an alert for every new article matching its query, in one
+// feed, in the feeds with a tag, or in all feeds if neither is set.
+type Rule struct {
+	ID        string
+	CreatedAt time.Time
+	Name      string
+	Query     string
+	FeedID    string
+	FeedName  string
+	Tag       string
+	Sinks     []string // where alerts are sent: stdout, a webhook URL or a mailto: address
+	Secret    string   // key of the HMAC signature of webhook payloads
+	Matches   int
+	LastMatch time.Time
+}
+
+// Digest is an email summarizing the articles added since the previous
+// one, sent daily or weekly to its recipients.
+type Digest struct {
+	ID            string
+	CreatedAt     time.Time
+	Name          string
+	Recipients    []string
+	Schedule      string // DigestDaily or DigestWeekly
+	SendTime      string // local time of day, as 15:04
+	Weekday       time.Weekday
+	GroupBy       string // DigestByFeed or DigestByTag
+	FeedID        string
+	FeedName      string
+	Tag           string
+	LastSentAt    time.Time
+	LastArticleAt time.Time // creation time of the last article sent
+	LastArticleID string    // id of the last article sent
+}
+
+// Digest schedules and groupings.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+	DigestByFeed = "feed"
+	DigestByTag  = "tag"
+)
+
+// DigestArticle is an article in a digest, with the feed it belongs to.
+type DigestArticle struct {
+	Article
+	FeedName  string
+	FeedTitle string
+	FeedTags  []string
+}
+
+// Story is a group of near-duplicate articles, possibly from several feeds,
+// represented by its earliest article.
+type Story struct {
+	Article  Article  `json:"article"`
+	Sources  []string `json:"sources"`
+	Articles int      `json:"articles"`
+}
+
+type ArticleRevision struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ArticleID   string    `json:"article_id"`
+	Title       string    `json:"title"`
+	PublishedAt time.Time `json:"published_at"`
+	Description string    `json:"description"`
+	Content     string    `json:"content"`
+	ContentHash string    `json:"content_hash"`
+}
+
+// ParsedFeed is a fetched feed normalized from any supported format.
+// Items carry everything but their ID and FeedID.
+type ParsedFeed struct {
+	Format        string
+	Title         string
+	Link          string
+	Description   string
+	Language      string
+	IconURL       string
+	LastBuildDate time.Time
+	Generator     string
+	TTL           int
+	Hubs          []string // WebSub hubs, from rel="hub" links
+	Self          string   // the URL the feed gives for itself
+	Items         []Article
+	Warnings      []string
+}
+
+// XML namespaces of the feed extensions understood by the parser. Fields
+// in these namespaces must come before unqualified fields of the same local
+// name, since encoding/xml assigns an element to the first matching field.
+const (
+	MediaNS   = "http://search.yahoo.com/mrss/"
+	ITunesNS  = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	DCNS      = "http://purl.org/dc/elements/1.1/"
+	ContentNS = "http://purl.org/rss/1.0/modules/content/"
+	AtomNS    = "http://www.w3.org/2005/Atom"
+)
+
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		ITunesImage   ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		AtomLinks     []AtomLink  `xml:"http://www.w3.org/2005/Atom link"`
+		Title         string      `xml:"title"`
+		Link          string      `xml:"link"`
+		Description   string      `xml:"description"`
+		Language      string      `xml:"language"`
+		Image         RSSImage    `xml:"image"`
+		LastBuildDate string      `xml:"lastBuildDate"`
+		Generator     string      `xml:"generator"`
+		TTL           string      `xml:"ttl"`
+		Item          []RSSItem   `xml:"item"`
 	} `xml:"channel"`
 }
 
+type RSSImage struct {
+	URL string `xml:"url"`
+}
+
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	MediaGroups     []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	MediaContents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	ITunesDuration  string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesImage     ITunesImage      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesSummary   string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	ITunesAuthor    string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	Creators        []string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	ContentEncoded  string           `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	GUID            RSSGUID          `xml:"guid"`
+	Title           string           `xml:"title"`
+	Link            string           `xml:"link"`
+	Description     string           `xml:"description"`
+	Author          string           `xml:"author"`
+	Categories      []string         `xml:"category"`
+	PubDate         string           `xml:"pubDate"`
+	Enclosures      []RSSEnclosure   `xml:"enclosure"`
+}
+
+type RSSGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type MediaGroup struct {
+	Contents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type MediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	Medium     string           `xml:"medium,attr"`
+	FileSize   string           `xml:"fileSize,attr"`
+	Duration   string           `xml:"duration,attr"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type AtomFeed struct {
+	XMLName   xml.Name    `xml:"feed"`
+	Lang      string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle"`
+	Link      []AtomLink  `xml:"link"`
+	Icon      string      `xml:"icon"`
+	Logo      string      `xml:"logo"`
+	Updated   string      `xml:"updated"`
+	Generator string      `xml:"generator"`
+	Entries   []AtomEntry `xml:"entry"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type AtomEntry struct {
+	MediaGroups     []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	MediaContents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	ID              string           `xml:"id"`
+	Title           string           `xml:"title"`
+	Link            []AtomLink       `xml:"link"`
+	Authors         []AtomPerson     `xml:"author"`
+	Categories      []AtomCategory   `xml:"category"`
+	Summary         string           `xml:"summary"`
+	Content         AtomContent      `xml:"content"`
+	Published       string           `xml:"published"`
+	Updated         string           `xml:"updated"`
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// AtomContent keeps both the text and the raw markup of <content>, since
+// type="xhtml" content is inline XML rather than escaped text.
+type AtomContent struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// JSONFeed is a JSON Feed (https://jsonfeed.org) document, version 1.0 or
+// 1.1.
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Icon        string           `json:"icon"`
+	Favicon     string           `json:"favicon"`
+	Language    string           `json:"language"`
+	Items       []JSONFeedItem   `json:"items"`
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Hubs        []JSONFeedHub    `json:"hubs"`
+}
+
+type JSONFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedItem struct {
+	ID            any                  `json:"id"` // a string, but some feeds use numbers
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	Image         string               `json:"image"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Author        *JSONFeedAuthor      `json:"author"` // version 1.0
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Tags          []string             `json:"tags"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MIMEType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
 }
@@ -0,0 +1,4 @@
+ALTER TABLE articles
+   DROP COLUMN IF EXISTS content,
+   DROP COLUMN IF EXISTS authors,
+   DROP COLUMN IF EXISTS categories;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE articles
+   ADD COLUMN IF NOT EXISTS content TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS authors TEXT[] NOT NULL DEFAULT '{}',
+   ADD COLUMN IF NOT EXISTS categories TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
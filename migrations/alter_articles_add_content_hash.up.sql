@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
\ No newline at end of file
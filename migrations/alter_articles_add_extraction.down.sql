@@ -0,0 +1,7 @@
+ALTER TABLE feeds DROP COLUMN IF EXISTS full_text;
+DROP INDEX IF EXISTS articles_extract_pending_idx;
+ALTER TABLE articles
+   DROP COLUMN IF EXISTS full_content,
+   DROP COLUMN IF EXISTS extracted_at,
+   DROP COLUMN IF EXISTS extract_attempts,
+   DROP COLUMN IF EXISTS extract_error;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE articles
+   ADD COLUMN IF NOT EXISTS full_content TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS extracted_at TIMESTAMP,
+   ADD COLUMN IF NOT EXISTS extract_attempts INTEGER NOT NULL DEFAULT 0,
+   ADD COLUMN IF NOT EXISTS extract_error TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS articles_extract_pending_idx ON articles (created_at) WHERE extracted_at IS NULL;
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS full_text BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
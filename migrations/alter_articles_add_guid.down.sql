@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS articles_feed_guid_idx;
+DROP INDEX IF EXISTS articles_feed_link_idx;
+CREATE UNIQUE INDEX articles_feed_link_idx ON articles (feed_id, link);
+ALTER TABLE articles DROP COLUMN IF EXISTS guid;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- Articles stored before GUIDs were kept are identified by their link, which
+-- was unique per feed.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS guid TEXT;
+UPDATE articles SET guid = link WHERE guid IS NULL;
+ALTER TABLE articles ALTER COLUMN guid SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS articles_feed_guid_idx ON articles (feed_id, guid);
+DROP INDEX IF EXISTS articles_feed_link_idx;
+CREATE INDEX articles_feed_link_idx ON articles (feed_id, link);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS plain_text TEXT NOT NULL DEFAULT '';
\ No newline at end of file
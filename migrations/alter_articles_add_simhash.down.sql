@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS articles_cluster_idx;
+ALTER TABLE articles DROP COLUMN IF EXISTS cluster_id;
+ALTER TABLE articles DROP COLUMN IF EXISTS simhash;
\ No newline at end of file
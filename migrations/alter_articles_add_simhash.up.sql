@@ -0,0 +1,5 @@
+-- Articles stored before clustering each start a story of their own.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS simhash BIGINT;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS cluster_id UUID;
+UPDATE articles SET cluster_id = id WHERE cluster_id IS NULL;
+CREATE INDEX IF NOT EXISTS articles_cluster_idx ON articles (cluster_id);
\ No newline at end of file
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS articles_simhash_band3_idx;
+DROP INDEX IF EXISTS articles_simhash_band2_idx;
+DROP INDEX IF EXISTS articles_simhash_band1_idx;
+DROP INDEX IF EXISTS articles_simhash_band0_idx;
+ALTER TABLE articles DROP COLUMN IF EXISTS simhash_band3;
+ALTER TABLE articles DROP COLUMN IF EXISTS simhash_band2;
+ALTER TABLE articles DROP COLUMN IF EXISTS simhash_band1;
+ALTER TABLE articles DROP COLUMN IF EXISTS simhash_band0;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- The 16-bit bands of the SimHash, so that stories are matched through an
+-- index: hashes that differ in at most 3 bits have at least one band in
+-- common.
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS simhash_band0 INTEGER GENERATED ALWAYS AS ((simhash & 65535)::integer) STORED;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS simhash_band1 INTEGER GENERATED ALWAYS AS (((simhash >> 16) & 65535)::integer) STORED;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS simhash_band2 INTEGER GENERATED ALWAYS AS (((simhash >> 32) & 65535)::integer) STORED;
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS simhash_band3 INTEGER GENERATED ALWAYS AS (((simhash >> 48) & 65535)::integer) STORED;
+CREATE INDEX IF NOT EXISTS articles_simhash_band0_idx ON articles (simhash_band0, published_at);
+CREATE INDEX IF NOT EXISTS articles_simhash_band1_idx ON articles (simhash_band1, published_at);
+CREATE INDEX IF NOT EXISTS articles_simhash_band2_idx ON articles (simhash_band2, published_at);
+CREATE INDEX IF NOT EXISTS articles_simhash_band3_idx ON articles (simhash_band3, published_at);
\ No newline at end of file
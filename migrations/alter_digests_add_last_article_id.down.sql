@@ -0,0 +1 @@
+ALTER TABLE digests DROP COLUMN IF EXISTS last_article_id;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- The id of the last article sent, which with last_article_at is the
+-- cursor the next digest starts after, so that articles added at the same
+-- time as it are not skipped.
+ALTER TABLE digests ADD COLUMN IF NOT EXISTS last_article_id UUID;
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE feeds
+   DROP COLUMN IF EXISTS title,
+   DROP COLUMN IF EXISTS site_url,
+   DROP COLUMN IF EXISTS description,
+   DROP COLUMN IF EXISTS language,
+   DROP COLUMN IF EXISTS icon_url,
+   DROP COLUMN IF EXISTS last_build_date,
+   DROP COLUMN IF EXISTS generator,
+   DROP COLUMN IF EXISTS ttl;
\ No newline at end of file
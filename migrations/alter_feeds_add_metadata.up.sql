@@ -0,0 +1,9 @@
+ALTER TABLE feeds
+   ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS site_url TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS icon_url TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS last_build_date TIMESTAMP,
+   ADD COLUMN IF NOT EXISTS generator TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS ttl INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
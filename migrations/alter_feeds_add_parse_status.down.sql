@@ -0,0 +1,3 @@
+ALTER TABLE feeds
+   DROP COLUMN IF EXISTS parse_status,
+   DROP COLUMN IF EXISTS parse_messages;
\ No newline at end of file
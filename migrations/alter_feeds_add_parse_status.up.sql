@@ -0,0 +1,3 @@
+ALTER TABLE feeds
+   ADD COLUMN IF NOT EXISTS parse_status TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS parse_messages TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
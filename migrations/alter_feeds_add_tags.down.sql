@@ -0,0 +1 @@
+ALTER TABLE feeds DROP COLUMN IF EXISTS tags;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
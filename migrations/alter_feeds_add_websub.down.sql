@@ -0,0 +1,3 @@
+ALTER TABLE feeds
+   DROP COLUMN IF EXISTS hub_url,
+   DROP COLUMN IF EXISTS self_url;
\ No newline at end of file
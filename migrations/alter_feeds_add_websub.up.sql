@@ -0,0 +1,3 @@
+ALTER TABLE feeds
+   ADD COLUMN IF NOT EXISTS hub_url TEXT NOT NULL DEFAULT '',
+   ADD COLUMN IF NOT EXISTS self_url TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE article_media (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   article_id UUID NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+   position INTEGER NOT NULL DEFAULT 0,
+   url TEXT NOT NULL,
+   mime_type TEXT NOT NULL DEFAULT '',
+   length BIGINT NOT NULL DEFAULT 0,
+   duration INTEGER NOT NULL DEFAULT 0,
+   thumbnail_url TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX article_media_article_idx ON article_media (article_id, position);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS article_revisions;
\ No newline at end of file
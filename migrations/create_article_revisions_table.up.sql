@@ -0,0 +1,11 @@
+CREATE TABLE article_revisions (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   article_id UUID NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+   title TEXT NOT NULL,
+   published_at TIMESTAMP NOT NULL,
+   description TEXT,
+   content TEXT NOT NULL DEFAULT '',
+   content_hash TEXT NOT NULL
+);
+CREATE INDEX article_revisions_article_idx ON article_revisions (article_id, created_at);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE digests (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   name TEXT NOT NULL UNIQUE,
+   recipients TEXT[] NOT NULL,
+   schedule TEXT NOT NULL DEFAULT 'daily',
+   send_time TEXT NOT NULL DEFAULT '08:00',
+   weekday INTEGER NOT NULL DEFAULT 1,
+   group_by TEXT NOT NULL DEFAULT 'feed',
+   feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+   tag TEXT NOT NULL DEFAULT '',
+   last_sent_at TIMESTAMP,
+   last_article_at TIMESTAMP
+);
\ No newline at end of file
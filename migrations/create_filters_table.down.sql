@@ -0,0 +1 @@
+DROP TABLE IF EXISTS filters;
\ No newline at end of file
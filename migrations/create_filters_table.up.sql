@@ -0,0 +1,12 @@
+CREATE TABLE filters (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+   position INTEGER NOT NULL,
+   action TEXT NOT NULL,
+   field TEXT NOT NULL DEFAULT '',
+   pattern TEXT NOT NULL DEFAULT '',
+   replacement TEXT NOT NULL DEFAULT '',
+   min_length INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX filters_feed_idx ON filters (feed_id, position);
\ No newline at end of file
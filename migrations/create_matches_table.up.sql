@@ -0,0 +1,8 @@
+CREATE TABLE matches (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   rule_id UUID NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+   article_id UUID NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+   UNIQUE (rule_id, article_id)
+);
+CREATE INDEX matches_article_idx ON matches (article_id);
\ No newline at end of file
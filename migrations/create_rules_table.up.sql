@@ -0,0 +1,10 @@
+CREATE TABLE rules (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   name TEXT NOT NULL UNIQUE,
+   query TEXT NOT NULL,
+   feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+   tag TEXT NOT NULL DEFAULT '',
+   sinks TEXT[] NOT NULL DEFAULT '{}',
+   secret TEXT NOT NULL DEFAULT ''
+);
\ No newline at end of file
@@ -0,0 +1,17 @@
+CREATE TABLE webhook_deliveries (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+   delivery_id UUID NOT NULL,
+   event TEXT NOT NULL,
+   attempt INTEGER NOT NULL,
+   state TEXT NOT NULL DEFAULT 'pending',
+   attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   payload BYTEA,
+   status_code INTEGER NOT NULL DEFAULT 0,
+   error TEXT NOT NULL DEFAULT '',
+   duration_ms INTEGER NOT NULL DEFAULT 0,
+   articles INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (attempt_at) WHERE state = 'pending';
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE webhooks (
+   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   url TEXT NOT NULL,
+   secret TEXT NOT NULL DEFAULT '',
+   feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+   tag TEXT NOT NULL DEFAULT '',
+   CHECK (feed_id IS NOT NULL OR tag <> '')
+);
+CREATE INDEX webhooks_feed_idx ON webhooks (feed_id);
+CREATE INDEX webhooks_tag_idx ON webhooks (tag);
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE websub_subscriptions (
+   feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   hub TEXT NOT NULL,
+   topic TEXT NOT NULL,
+   secret TEXT NOT NULL,
+   state TEXT NOT NULL DEFAULT 'pending',
+   expires_at TIMESTAMP,
+   requested_at TIMESTAMP
+);
+CREATE INDEX websub_subscriptions_expires_idx ON websub_subscriptions (expires_at) WHERE state = 'active';
\ No newline at end of file
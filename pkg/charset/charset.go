@@ -0,0 +1,142 @@
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// aliases maps labels seen in feeds that the WHATWG Encoding Standard does
+// not list to ones it does.
+var aliases = map[string]string{
+	"cp932":   "shift_jis",
+	"koi8r":   "koi8-r",
+	"latin-1": "latin1",
+	"latin-2": "latin2",
+	"latin9":  "iso-8859-15",
+	"latin-9": "iso-8859-15",
+}
+
+var (
+	xmlDeclaration = regexp.MustCompile(`^<\?xml[^>]*?\?>`)
+	xmlEncoding    = regexp.MustCompile(`(encoding\s*=\s*)("[^"]*"|'[^']*')`)
+	metaCharset    = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.+-]+)`)
+)
+
+// Lookup returns the canonical name of a charset label, or "" if it is not
+// supported. Labels are resolved as in browsers, following the WHATWG
+// Encoding Standard: US-ASCII and ISO-8859-1 are decoded as Windows-1252,
+// since feeds that declare them routinely contain its curly quotes and
+// dashes, and GB2312 as GBK.
+func Lookup(label string) string {
+	enc, err := lookup(label)
+	if err != nil {
+		return ""
+	}
+	name, _ := htmlindex.Name(enc)
+	return name
+}
+
+func lookup(label string) (encoding.Encoding, error) {
+	label = strings.ToLower(strings.Trim(strings.TrimSpace(label), `"'`))
+	if alias, ok := aliases[label]; ok {
+		label = alias
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil || enc == encoding.Replacement {
+		return nil, fmt.Errorf("unsupported charset %q", label)
+	}
+	return enc, nil
+}
+
+// Detect returns the charset of body, taken from its byte order mark, the
+// charset parameter of contentType, its XML declaration or an HTML <meta>
+// tag, in that order. It returns "" if none of them names one.
+func Detect(body []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(body, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte("\xff\xfe")):
+		return "utf-16le"
+	case bytes.HasPrefix(body, []byte("\xfe\xff")):
+		return "utf-16be"
+	}
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+			return params["charset"]
+		}
+	}
+	if decl := xmlDeclaration.Find(bytes.TrimLeft(body, " \t\r\n")); decl != nil {
+		if m := xmlEncoding.FindSubmatch(decl); m != nil {
+			return string(bytes.Trim(m[2], `"'`))
+		}
+		return ""
+	}
+	head := body[:min(len(body), 1024)]
+	if m := metaCharset.FindSubmatch(head); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// ToUTF8 converts body to UTF-8 using the charset found by Detect. Bodies
+// without a declared charset are taken to be UTF-8, unless they are not
+// valid UTF-8, in which case they are decoded as Windows-1252. The encoding
+// of an XML declaration is rewritten to UTF-8 so that the result can be
+// handed to encoding/xml.
+func ToUTF8(body []byte, contentType string) ([]byte, error) {
+	label := Detect(body, contentType)
+	name := Lookup(label)
+	switch {
+	case label == "" && utf8.Valid(body):
+		name = "utf-8"
+	case label == "":
+		name = "windows-1252"
+	case name == "":
+		return nil, fmt.Errorf("unsupported charset %q", label)
+	}
+	out, err := Decode(body, name)
+	if err != nil {
+		return nil, err
+	}
+	return rewriteDeclaration(out), nil
+}
+
+// Decode converts body from the named charset to UTF-8, replacing bytes
+// that do not map to a character with U+FFFD.
+func Decode(body []byte, name string) ([]byte, error) {
+	enc, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if enc == unicode.UTF8 {
+		return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), nil
+	}
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(out, []byte("\xef\xbb\xbf")), nil
+}
+
+// rewriteDeclaration makes the XML declaration of a converted document, if
+// it has one with an encoding, declare UTF-8.
+func rewriteDeclaration(body []byte) []byte {
+	start := len(body) - len(bytes.TrimLeft(body, " \t\r\n"))
+	decl := xmlDeclaration.Find(body[start:])
+	if decl == nil || !xmlEncoding.Match(decl) {
+		return body
+	}
+	fixed := xmlEncoding.ReplaceAll(decl, []byte(`${1}"UTF-8"`))
+	out := make([]byte, 0, len(body)+len(fixed)-len(decl))
+	out = append(out, body[:start]...)
+	out = append(out, fixed...)
+	return append(out, body[start+len(decl):]...)
+}
@@ -0,0 +1,134 @@
+package charset
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"UTF-8", "utf-8"},
+		{" utf8 ", "utf-8"},
+		{`"utf-16"`, "utf-16le"},
+		{"UTF-16BE", "utf-16be"},
+		{"us-ascii", "windows-1252"},
+		{"ISO-8859-1", "windows-1252"},
+		{"latin-1", "windows-1252"},
+		{"cp1251", "windows-1251"},
+		{"koi8r", "koi8-r"},
+		{"koi8-u", "koi8-u"},
+		{"latin2", "iso-8859-2"},
+		{"latin-9", "iso-8859-15"},
+		{"Shift_JIS", "shift_jis"},
+		{"cp932", "shift_jis"},
+		{"windows-31j", "shift_jis"},
+		{"EUC-JP", "euc-jp"},
+		{"gb2312", "gbk"},
+		{"GBK", "gbk"},
+		{"gb18030", "gb18030"},
+		{"big5", "big5"},
+		{"euc-kr", "euc-kr"},
+		{"iso-2022-kr", ""},
+		{"klingon", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Lookup(tt.label); got != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		charset string
+		in      string
+		want    string
+	}{
+		{"utf-8", "\xef\xbb\xbfcaf\xc3\xa9", "café"},
+		{"utf-16le", "\xff\xfec\x00a\x00f\x00\xe9\x00", "café"},
+		{"utf-16be", "\x00c\x00a\x00f\x00\xe9", "café"},
+		{"windows-1252", "\x93caf\xe9\x94 \x96 \x80", "“café” – €"},
+		{"iso-8859-1", "caf\xe9 \x85", "café …"},
+		{"windows-1251", "\xcf\xf0\xe8\xe2\xe5\xf2", "Привет"},
+		{"koi8-r", "\xf0\xd2\xc9\xd7\xc5\xd4", "Привет"},
+		{"koi8-u", "\xa4\xb4", "єЄ"},
+		{"iso-8859-2", "\xa3\xf3d\xbc", "Łódź"},
+		{"iso-8859-15", "\xa4 \xbd", "€ œ"},
+		{"shift_jis", "\x93\xfa\x96{\x8c\xea \xb1\xb2", "日本語 ｱｲ"},
+		{"euc-jp", "\xc6\xfc\xcb\xdc\xb8\xec", "日本語"},
+		{"gbk", "\xd6\xd0\xce\xc4", "中文"},
+		{"gb18030", "\x81\x30\x81\x30", "\u0080"},
+		{"big5", "\xa4\xa4\xa4\xe5", "中文"},
+		{"euc-kr", "\xc7\xd1\xb1\xb9\xbe\xee", "한국어"},
+		// Bytes that map to nothing become U+FFFD.
+		{"shift_jis", "\x93", "�"},
+		{"windows-1251", "\x98", "�"},
+	}
+	for _, tt := range tests {
+		got, err := Decode([]byte(tt.in), tt.charset)
+		if err != nil {
+			t.Errorf("Decode(%q, %s): %v", tt.in, tt.charset, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Decode(%q, %s) = %q, want %q", tt.in, tt.charset, got, tt.want)
+		}
+	}
+	if _, err := Decode([]byte("x"), "klingon"); err == nil {
+		t.Error("Decode with an unknown charset succeeded")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+	}{
+		{"UTF-8 BOM", "\xef\xbb\xbf<rss/>", "text/xml; charset=windows-1251", "utf-8"},
+		{"UTF-16 BOM", "\xff\xfe<\x00", "", "utf-16le"},
+		{"content type", `<?xml version="1.0" encoding="utf-8"?><rss/>`, "application/rss+xml; charset=KOI8-R", "KOI8-R"},
+		{"XML declaration", `<?xml version="1.0" encoding='Shift_JIS'?><rss/>`, "application/rss+xml", "Shift_JIS"},
+		{"XML declaration without encoding", `<?xml version="1.0"?><meta charset="gbk">`, "", ""},
+		{"meta charset", `<html><head><meta charset="big5">`, "text/html", "big5"},
+		{"meta http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=euc-jp">`, "", "euc-jp"},
+		{"none", `<rss/>`, "", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect([]byte(tt.body), tt.contentType); got != tt.want {
+			t.Errorf("%s: Detect = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+		wantErr     bool
+	}{
+		{"declared", "<?xml version=\"1.0\" encoding=\"windows-1251\"?>\n<t>\xcf\xf0\xe8\xe2\xe5\xf2</t>", "",
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<t>Привет</t>", false},
+		{"header wins", "<?xml version='1.0' encoding='iso-8859-1'?><t>\xd6\xd0\xce\xc4</t>", "text/xml; charset=gbk",
+			`<?xml version='1.0' encoding="UTF-8"?><t>中文</t>`, false},
+		{"undeclared UTF-8", "<t>café</t>", "", "<t>café</t>", false},
+		{"undeclared other", "<t>caf\xe9</t>", "", "<t>café</t>", false},
+		{"unsupported", `<?xml version="1.0" encoding="klingon"?><t/>`, "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ToUTF8([]byte(tt.body), tt.contentType)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: ToUTF8 = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package dom
+
+import (
+	"html"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type NodeType int
+
+const (
+	DocumentNode NodeType = iota
+	ElementNode
+	TextNode
+	CommentNode
+)
+
+// Node is an element, text or comment of a parsed document. For elements
+// Data is the lowercased tag name; for text and comments it is the content.
+type Node struct {
+	Type     NodeType
+	Data     string
+	Attrs    []Attr
+	Parent   *Node
+	Children []*Node
+}
+
+// Attr is an attribute of an element. Keys are lowercased.
+type Attr struct {
+	Key string
+	Val string
+}
+
+// voidElements never have content or an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold text that is rendered as is rather than escaped.
+var rawTextElements = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true, "plaintext": true, "script": true,
+	"style": true, "xmp": true,
+}
+
+// Parse builds the tree of an HTML document the way browsers do, adding the
+// html, head and body elements if they are missing. It never fails: markup
+// errors are recovered from as the HTML standard specifies. Documents are
+// parsed as with scripting disabled, so that the content of <noscript> is
+// markup rather than text that a sanitizer could not see into.
+func Parse(s string) *Node {
+	doc, err := nethtml.ParseWithOptions(strings.NewReader(s), nethtml.ParseOptionEnableScripting(false))
+	if err != nil {
+		// Only read errors are returned, and strings.Reader has none.
+		return &Node{Type: DocumentNode}
+	}
+	return convert(doc)
+}
+
+// ParseFragment builds the tree of a fragment of HTML, such as the content
+// of a feed item, parsed as the content of a <body>. The returned document
+// node holds the fragment's top-level nodes. Scripting is disabled as for
+// Parse.
+func ParseFragment(s string) *Node {
+	doc := &Node{Type: DocumentNode}
+	body := &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := nethtml.ParseFragmentWithOptions(strings.NewReader(s), body, nethtml.ParseOptionEnableScripting(false))
+	if err != nil {
+		return doc
+	}
+	for _, n := range nodes {
+		if c := convert(n); c != nil {
+			doc.AppendChild(c)
+		}
+	}
+	return doc
+}
+
+// convert returns the tree of n, or nil for doctypes.
+func convert(n *nethtml.Node) *Node {
+	var c *Node
+	switch n.Type {
+	case nethtml.DocumentNode:
+		c = &Node{Type: DocumentNode}
+	case nethtml.ElementNode:
+		// SVG and MathML elements keep the case of their names, like
+		// foreignObject; lowercase them like the rest.
+		c = &Node{Type: ElementNode, Data: strings.ToLower(n.Data)}
+		for _, a := range n.Attr {
+			key := a.Key
+			if a.Namespace != "" {
+				key = a.Namespace + ":" + key
+			}
+			c.Attrs = append(c.Attrs, Attr{Key: key, Val: a.Val})
+		}
+	case nethtml.TextNode:
+		return &Node{Type: TextNode, Data: n.Data}
+	case nethtml.CommentNode:
+		return &Node{Type: CommentNode, Data: n.Data}
+	default:
+		return nil
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if cc := convert(child); cc != nil {
+			c.AppendChild(cc)
+		}
+	}
+	return c
+}
+
+func (n *Node) AppendChild(child *Node) {
+	child.Parent = n
+	n.Children = append(n.Children, child)
+}
+
+// Attr returns the value of the attribute, or "" if it is not set.
+func (n *Node) Attr(key string) string {
+	for _, a := range n.Attrs {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// HasAttr reports whether the attribute is set.
+func (n *Node) HasAttr(key string) bool {
+	for _, a := range n.Attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Text returns the concatenated text of the node and its descendants.
+func (n *Node) Text() string {
+	var b strings.Builder
+	n.Walk(func(c *Node) bool {
+		if c.Type == TextNode {
+			b.WriteString(c.Data)
+		}
+		return !(c.Type == ElementNode && (c.Data == "script" || c.Data == "style"))
+	})
+	return b.String()
+}
+
+// Walk calls fn for the node and its descendants in document order. If fn
+// returns false the children of that node are skipped.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// Find returns the first element, in document order, with the tag name.
+func (n *Node) Find(tag string) *Node {
+	var found *Node
+	n.Walk(func(c *Node) bool {
+		if found == nil && c.Type == ElementNode && c.Data == tag {
+			found = c
+		}
+		return found == nil
+	})
+	return found
+}
+
+// FindAll returns all elements with the tag name in document order.
+func (n *Node) FindAll(tag string) []*Node {
+	var found []*Node
+	n.Walk(func(c *Node) bool {
+		if c.Type == ElementNode && c.Data == tag {
+			found = append(found, c)
+		}
+		return true
+	})
+	return found
+}
+
+// Render serializes the node and its descendants back to HTML.
+func Render(n *Node) string {
+	var b strings.Builder
+	render(&b, n)
+	return b.String()
+}
+
+// RenderChildren serializes the descendants of the node, without the node
+// itself.
+func RenderChildren(n *Node) string {
+	var b strings.Builder
+	for _, c := range n.Children {
+		render(&b, c)
+	}
+	return b.String()
+}
+
+func render(b *strings.Builder, n *Node) {
+	switch n.Type {
+	case DocumentNode:
+		for _, c := range n.Children {
+			render(b, c)
+		}
+	case TextNode:
+		if n.Parent != nil && n.Parent.Type == ElementNode && rawTextElements[n.Parent.Data] {
+			b.WriteString(n.Data)
+		} else {
+			b.WriteString(html.EscapeString(n.Data))
+		}
+	case CommentNode:
+		b.WriteString("<!--" + n.Data + "-->")
+	case ElementNode:
+		b.WriteString("<" + n.Data)
+		for _, a := range n.Attrs {
+			b.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+		}
+		b.WriteString(">")
+		if voidElements[n.Data] {
+			return
+		}
+		for _, c := range n.Children {
+			render(b, c)
+		}
+		b.WriteString("</" + n.Data + ">")
+	}
+}
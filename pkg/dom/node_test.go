@@ -0,0 +1,140 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+// outline describes the elements and text of a tree, like
+// p(a[href=x]("link") "text"), for comparing parse results.
+func outline(n *Node) string {
+	var b strings.Builder
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for i, c := range n.Children {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			switch c.Type {
+			case TextNode:
+				b.WriteString(`"` + c.Data + `"`)
+			case CommentNode:
+				b.WriteString("<!--" + c.Data + "-->")
+			case ElementNode:
+				b.WriteString(c.Data)
+				for _, a := range c.Attrs {
+					b.WriteString("[" + a.Key + "=" + a.Val + "]")
+				}
+				if len(c.Children) > 0 {
+					b.WriteString("(")
+					walk(c)
+					b.WriteString(")")
+				}
+			}
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func TestParseFragment(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"text", "hello", `"hello"`},
+		{"nested", "<p>a <b>b</b></p>", `p("a " b("b"))`},
+		{"uppercase", "<P CLASS=x>a</P>", `p[class=x]("a")`},
+		{"void", "a<br>b<img src=x>c", `"a" br "b" img[src=x] "c"`},
+		{"self-closing", "<br/><span/>x", `br span("x")`},
+
+		// Malformed and unclosed markup.
+		{"unclosed", "<div><p>a", `div(p("a"))`},
+		{"unclosed inline", "<b>a<i>b</b>c", `b("a" i("b")) i("c")`},
+		{"stray end tag", "a</span>b", `"ab"`},
+		{"implied p end", "<p>a<p>b<div>c</div>", `p("a") p("b") div("c")`},
+		{"implied li end", "<ul><li>a<li>b</ul>", `ul(li("a") li("b"))`},
+		{"stray lt", "a < b <3", `"a < b <3"`},
+		{"unterminated tag", "a<b", `"a"`},
+		{"unterminated comment", "a<!-- b", `"a" <!-- b-->`},
+		{"comment", "a<!-- <b>x</b> -->c", `"a" <!-- <b>x</b> --> "c"`},
+		{"doctype", "<!DOCTYPE html><p>a", `p("a")`},
+
+		// Entities.
+		{"named entities", "&lt;&amp;&gt;&quot;&copy;", `"<&>"©"`},
+		{"numeric entities", "&#65;&#x42;&#x1F600;", `"AB😀"`},
+		{"entity without semicolon", "&amp &lt", `"& <"`},
+		{"unknown entity", "&bogus; &", `"&bogus; &"`},
+
+		// Attribute quoting.
+		{"double quoted", `<a href="x y">a</a>`, `a[href=x y]("a")`},
+		{"single quoted", `<a href='x "y"'>a</a>`, `a[href=x "y"]("a")`},
+		{"unquoted", `<a href=x title=y>a</a>`, `a[href=x][title=y]("a")`},
+		{"no value", `<input disabled>`, `input[disabled=]`},
+		{"entity in value", `<a href="?a=1&amp;b=2">a</a>`, `a[href=?a=1&b=2]("a")`},
+		{"gt in quoted value", `<a title="a>b">c</a>`, `a[title=a>b]("c")`},
+
+		// Raw text elements.
+		{"script", `<script>if (a < b && c) "</b>"</script>x`, `script("if (a < b && c) "</b>"") "x"`},
+		{"style", `<style>p > a { color: red }</style>`, `style("p > a { color: red }")`},
+		{"script end tag case", `<script>a</SCRIPT >b`, `script("a") "b"`},
+		{"unclosed script", `<script>a<b>c`, `script("a<b>c")`},
+		{"textarea", `<textarea><b>&amp;</b></textarea>`, `textarea("<b>&</b>")`},
+		{"title", `<title>a &amp; <b></title>`, `title("a & <b>")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outline(ParseFragment(tt.html)); got != tt.want {
+				t.Errorf("ParseFragment(%q)\n got %s\nwant %s", tt.html, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc := Parse(`<!DOCTYPE html><title>T</title><link rel=alternate href=/feed><p>a`)
+	if got, want := outline(doc), `html(head(title("T") link[rel=alternate][href=/feed]) body(p("a")))`; got != want {
+		t.Errorf("Parse\n got %s\nwant %s", got, want)
+	}
+	p := doc.Find("p")
+	if p == nil || p.Parent.Data != "body" || p.Parent.Parent.Data != "html" || p.Parent.Parent.Parent != doc {
+		t.Errorf("parents of p not linked")
+	}
+}
+
+func TestAttr(t *testing.T) {
+	a := ParseFragment(`<a ID=x id=y data-empty>a</a>`).Find("a")
+	if got := a.Attr("id"); got != "x" {
+		t.Errorf(`Attr("id") = %q, want the first value "x"`, got)
+	}
+	if !a.HasAttr("data-empty") || a.Attr("data-empty") != "" {
+		t.Errorf("empty attribute not set")
+	}
+	if a.HasAttr("href") {
+		t.Errorf("missing attribute set")
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{`<p class=x>a &amp; b</p>`, `<p class="x">a &amp; b</p>`},
+		{`<a title='"<'>x</a>`, `<a title="&#34;&lt;">x</a>`},
+		{`a<br>b<img src=x>`, `a<br>b<img src="x">`},
+		{`<div><p>a</div>`, `<div><p>a</p></div>`},
+		{`<script>a < b</script>`, `<script>a < b</script>`},
+		{`<!--c-->`, `<!--c-->`},
+	}
+	for _, tt := range tests {
+		if got := Render(ParseFragment(tt.html)); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	doc := ParseFragment(`<p>a <b>b</b></p><script>x</script><style>y</style>c`)
+	if got, want := doc.Text(), "a bc"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,297 @@
+package dom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector is a compiled CSS selector. The supported subset covers what
+// scraping rules need: type, #id, .class and [attr] selectors (with =, ~=,
+// ^=, $= and *=), :first-child, :last-child and :nth-child(n), the
+// descendant and child combinators, and comma-separated groups.
+type Selector struct {
+	groups [][]compound // each group is matched right to left
+}
+
+type compound struct {
+	combinator byte // ' ' or '>' relating it to the previous compound
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrMatcher
+	nth        int // 1-based position among element siblings; -1 for last
+}
+
+type attrMatcher struct {
+	key, op, val string
+}
+
+// Compile parses a CSS selector.
+func Compile(sel string) (*Selector, error) {
+	s := &Selector{}
+	for _, group := range strings.Split(sel, ",") {
+		compounds, err := parseGroup(strings.TrimSpace(group))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", sel, err)
+		}
+		s.groups = append(s.groups, compounds)
+	}
+	return s, nil
+}
+
+func parseGroup(s string) ([]compound, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	var compounds []compound
+	combinator := byte(' ')
+	for i := 0; i < len(s); {
+		switch {
+		case isSpace(s[i]):
+			i++
+			continue
+		case s[i] == '>':
+			if len(compounds) == 0 {
+				return nil, fmt.Errorf("selector starts with >")
+			}
+			combinator = '>'
+			i++
+			continue
+		}
+		c, n, err := parseCompound(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		c.combinator = combinator
+		combinator = ' '
+		compounds = append(compounds, c)
+		i += n
+	}
+	if combinator == '>' {
+		return nil, fmt.Errorf("selector ends with >")
+	}
+	return compounds, nil
+}
+
+func parseCompound(s string) (compound, int, error) {
+	c := compound{}
+	i := 0
+	name := func() string {
+		start := i
+		for i < len(s) && (isLetter(s[i]) || (s[i] >= '0' && s[i] <= '9') || s[i] == '-' || s[i] == '_') {
+			i++
+		}
+		return s[start:i]
+	}
+	if i < len(s) && s[i] == '*' {
+		i++
+	} else {
+		c.tag = strings.ToLower(name())
+	}
+	for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+		switch s[i] {
+		case '#':
+			i++
+			c.id = name()
+		case '.':
+			i++
+			c.classes = append(c.classes, name())
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return c, 0, fmt.Errorf("unterminated [")
+			}
+			m, err := parseAttr(s[i+1 : i+end])
+			if err != nil {
+				return c, 0, err
+			}
+			c.attrs = append(c.attrs, m)
+			i += end + 1
+		case ':':
+			i++
+			pseudo := name()
+			switch pseudo {
+			case "first-child":
+				c.nth = 1
+			case "last-child":
+				c.nth = -1
+			case "nth-child":
+				end := strings.IndexByte(s[i:], ')')
+				if i >= len(s) || s[i] != '(' || end < 0 {
+					return c, 0, fmt.Errorf("invalid :nth-child")
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(s[i+1 : i+end]))
+				if err != nil || n < 1 {
+					return c, 0, fmt.Errorf("invalid :nth-child")
+				}
+				c.nth = n
+				i += end + 1
+			default:
+				return c, 0, fmt.Errorf("unsupported pseudo-class :%s", pseudo)
+			}
+		default:
+			return c, 0, fmt.Errorf("unexpected %q", s[i])
+		}
+	}
+	if i == 0 {
+		return c, 0, fmt.Errorf("unexpected %q", s[0])
+	}
+	return c, i, nil
+}
+
+func parseAttr(s string) (attrMatcher, error) {
+	for _, op := range []string{"~=", "^=", "$=", "*=", "="} {
+		if k := strings.Index(s, op); k > 0 {
+			val := strings.TrimSpace(s[k+len(op):])
+			val = strings.Trim(val, `"'`)
+			return attrMatcher{key: strings.ToLower(strings.TrimSpace(s[:k])), op: op, val: val}, nil
+		}
+	}
+	key := strings.ToLower(strings.TrimSpace(s))
+	if key == "" {
+		return attrMatcher{}, fmt.Errorf("empty attribute selector")
+	}
+	return attrMatcher{key: key}, nil
+}
+
+// Match reports whether the element matches the selector.
+func (s *Selector) Match(n *Node) bool {
+	for _, group := range s.groups {
+		if matchGroup(n, group) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGroup(n *Node, group []compound) bool {
+	last := len(group) - 1
+	if !group[last].match(n) {
+		return false
+	}
+	return matchAncestors(n, group[:last], group[last].combinator)
+}
+
+// matchAncestors matches the remaining compounds against the ancestors of
+// n, where combinator relates n to the last of them.
+func matchAncestors(n *Node, group []compound, combinator byte) bool {
+	if len(group) == 0 {
+		return true
+	}
+	last := len(group) - 1
+	for p := n.Parent; p != nil && p.Type == ElementNode; p = p.Parent {
+		if group[last].match(p) && matchAncestors(p, group[:last], group[last].combinator) {
+			return true
+		}
+		if combinator == '>' {
+			return false
+		}
+	}
+	return false
+}
+
+func (c compound) match(n *Node) bool {
+	if n.Type != ElementNode || (c.tag != "" && n.Data != c.tag) {
+		return false
+	}
+	if c.id != "" && n.Attr("id") != c.id {
+		return false
+	}
+	classes := strings.Fields(n.Attr("class"))
+	for _, class := range c.classes {
+		if !contains(classes, class) {
+			return false
+		}
+	}
+	for _, m := range c.attrs {
+		if !n.HasAttr(m.key) || !m.match(n.Attr(m.key)) {
+			return false
+		}
+	}
+	if c.nth != 0 && !nthChild(n, c.nth) {
+		return false
+	}
+	return true
+}
+
+func (m attrMatcher) match(v string) bool {
+	switch m.op {
+	case "=":
+		return v == m.val
+	case "~=":
+		return contains(strings.Fields(v), m.val)
+	case "^=":
+		return m.val != "" && strings.HasPrefix(v, m.val)
+	case "$=":
+		return m.val != "" && strings.HasSuffix(v, m.val)
+	case "*=":
+		return m.val != "" && strings.Contains(v, m.val)
+	}
+	return true
+}
+
+func nthChild(n *Node, nth int) bool {
+	if n.Parent == nil {
+		return false
+	}
+	var siblings []*Node
+	for _, c := range n.Parent.Children {
+		if c.Type == ElementNode {
+			siblings = append(siblings, c)
+		}
+	}
+	if nth == -1 {
+		return len(siblings) > 0 && siblings[len(siblings)-1] == n
+	}
+	return nth <= len(siblings) && siblings[nth-1] == n
+}
+
+// QueryAll returns the descendants of n matching the selector, in
+// document order.
+func (n *Node) QueryAll(s *Selector) []*Node {
+	var found []*Node
+	for _, c := range n.Children {
+		c.Walk(func(d *Node) bool {
+			if s.Match(d) {
+				found = append(found, d)
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// Query returns the first descendant of n matching the selector, or nil.
+func (n *Node) Query(s *Selector) *Node {
+	var found *Node
+	for _, c := range n.Children {
+		c.Walk(func(d *Node) bool {
+			if found == nil && s.Match(d) {
+				found = d
+			}
+			return found == nil
+		})
+		if found != nil {
+			break
+		}
+	}
+	return found
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
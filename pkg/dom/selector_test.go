@@ -0,0 +1,116 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+const page = `<div id="main" class="content wide">
+  <ul class="posts">
+    <li class="post first"><a href="/a" data-id="1">A</a><span class="date">1</span></li>
+    <li class="post"><a href="/b" rel="nofollow external">B</a></li>
+    <li class="post sponsored"><a href="https://ads.example/c">C</a></li>
+  </ul>
+  <section><p><a href="/d.pdf">D</a></p></section>
+</div>
+<a href="/e">E</a>`
+
+func TestSelector(t *testing.T) {
+	doc := ParseFragment(page)
+	tests := []struct {
+		selector string
+		want     string // text of the matches
+	}{
+		{"a", "A B C D E"},
+		{"ul > *", "A1 B C"},
+		{"li > *", "A 1 B C"},
+		{"#main a", "A B C D"},
+		{"li.post", "A1 B C"},
+		{".post.first a", "A"},
+		{"LI.POST", ""},
+		{"ul > li > a", "A B C"},
+		{"div > a", ""},
+		{"div a", "A B C D"},
+		{"div > section a", "D"},
+		{"div>ul>li>span", "1"},
+		{"#main > ul li:first-child a", "A"},
+		{"li:last-child a", "C"},
+		{"li:nth-child(2) a", "B"},
+		{"li:nth-child(4)", ""},
+		{"a:first-child", "A B C D"},
+		{"[data-id]", "A"},
+		{`a[href="/b"]`, "B"},
+		{"a[href='/b']", "B"},
+		{"a[rel~=external]", "B"},
+		{"a[rel~=extern]", ""},
+		{"a[href^=https]", "C"},
+		{"a[href$=.pdf]", "D"},
+		{"a[href*=ads]", "C"},
+		{"a[href^='']", ""},
+		{"li.sponsored a, section a", "C D"},
+		{"section a, li.sponsored a", "C D"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			s, err := Compile(tt.selector)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tt.selector, err)
+			}
+			var texts []string
+			for _, n := range doc.QueryAll(s) {
+				texts = append(texts, strings.TrimSpace(n.Text()))
+			}
+			if got := strings.Join(texts, " "); got != tt.want {
+				t.Errorf("QueryAll(%q) = %q, want %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	doc := ParseFragment(page)
+	s, err := Compile("li a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := doc.Query(s); n == nil || n.Attr("href") != "/a" {
+		t.Errorf("Query(li a) = %v, want the first link", n)
+	}
+	ul := doc.Find("ul")
+	if n := ul.Query(mustCompile(t, "ul")); n != nil {
+		t.Errorf("Query matched the node itself")
+	}
+	if n := ul.Query(mustCompile(t, "section")); n != nil {
+		t.Errorf("Query matched outside the node")
+	}
+}
+
+func mustCompile(t *testing.T, sel string) *Selector {
+	t.Helper()
+	s, err := Compile(sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, sel := range []string{
+		"",
+		"a,",
+		"> a",
+		"a >",
+		"a[href",
+		"a[]",
+		"a:hover",
+		"li:nth-child(0)",
+		"li:nth-child(odd)",
+		"li:nth-child(2",
+		"a + b",
+		"a ~ b",
+	} {
+		if _, err := Compile(sel); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", sel)
+		}
+	}
+}
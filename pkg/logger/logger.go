@@ -2,27 +2,59 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 )
 
-var Log *slog.Logger
-
-func Init(env string) {
-	opts := slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	}
+// Formats of the log output.
+const (
+	FormatPretty = "pretty" // colored, one line per record, for people
+	FormatText   = "text"   // logfmt key=value pairs
+	FormatJSON   = "json"   // one JSON object per record
+)
 
-	var handler slog.Handler
+// Log is the logger of the application. It logs through the default slog
+// logger until Init is called.
+var Log = slog.Default()
 
-	if env == "development" {
-		handler = NewPrettyHandler(os.Stdout, opts)
-	} else {
-		handler = slog.NewJSONHandler(os.Stdout, &opts)
+// Init makes the logger write records at level and above to stderr in
+// format, and makes it the default slog logger.
+func Init(format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q, use debug, info, warn or error", level)
+	}
+	handler, err := NewHandler(os.Stderr, format, lvl)
+	if err != nil {
+		return err
 	}
-
 	Log = slog.New(handler)
 	slog.SetDefault(Log)
+	return nil
+}
+
+// NewHandler returns a handler that writes records at level and above to
+// out in format.
+func NewHandler(out io.Writer, format string, level slog.Level) (slog.Handler, error) {
+	opts := slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case FormatPretty, "":
+		return NewPrettyHandler(out, opts), nil
+	case FormatText:
+		return slog.NewTextHandler(out, &opts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(out, &opts), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q, use pretty, text or json", format)
+}
+
+// With returns a logger that adds args to every record, e.g. the name of
+// a component.
+func With(args ...any) *slog.Logger {
+	return Log.With(args...)
 }
 
 func Info(msg string, args ...any) {
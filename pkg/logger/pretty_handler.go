@@ -2,52 +2,165 @@ package logger
 
 import (
 	"context"
-	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
+// PrettyHandler writes records as single colored lines:
+//
+//	15:04:05 [INFO] Feed fetched feed=lwn new=3 duration=412ms
+//
+// Attributes in groups are written with dotted keys. Colors are only used
+// when writing to a terminal.
 type PrettyHandler struct {
-	out    *os.File
+	out    io.Writer
+	mu     *sync.Mutex
 	opts   slog.HandlerOptions
+	color  bool
 	levels map[slog.Level]string
+
+	// attrs are the attributes added by WithAttrs, already formatted, and
+	// prefix is the dotted path of the groups opened by WithGroup.
+	attrs  string
+	prefix string
 }
 
-func NewPrettyHandler(out *os.File, opts slog.HandlerOptions) slog.Handler {
-	return &PrettyHandler{
-		out:  out,
-		opts: opts,
-		levels: map[slog.Level]string{
-			slog.LevelDebug: colorGray("[DEBUG]"),
-			slog.LevelInfo:  colorCyan("[INFO]"),
-			slog.LevelWarn:  colorYellow("[WARN]"),
-			slog.LevelError: colorRed("[ERROR]"),
-		},
+func NewPrettyHandler(out io.Writer, opts slog.HandlerOptions) slog.Handler {
+	h := &PrettyHandler{
+		out:   out,
+		mu:    &sync.Mutex{},
+		opts:  opts,
+		color: isTerminal(out),
+	}
+	h.levels = map[slog.Level]string{
+		slog.LevelDebug: h.paint(colorGray, "[DEBUG]"),
+		slog.LevelInfo:  h.paint(colorCyan, "[INFO]"),
+		slog.LevelWarn:  h.paint(colorYellow, "[WARN]"),
+		slog.LevelError: h.paint(colorRed, "[ERROR]"),
 	}
+	return h
 }
 
 func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
-	return level >= h.opts.Level.Level()
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return level >= min
 }
 
 func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
-	levelStr := h.levels[r.Level]
-	timestamp := r.Time.Format("15:04:05")
+	levelStr, ok := h.levels[r.Level]
+	if !ok {
+		levelStr = "[" + r.Level.String() + "]"
+	}
 
-	var attrs []string
+	var b strings.Builder
+	// Like the handlers of log/slog, leave out the time of records that
+	// have none.
+	if !r.Time.IsZero() {
+		b.WriteString(h.paint(colorGray, r.Time.Format("15:04:05")))
+		b.WriteString(" ")
+	}
+	b.WriteString(levelStr)
+	b.WriteString(" ")
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
 	r.Attrs(func(a slog.Attr) bool {
-		attrs = append(attrs, fmt.Sprintf("%s=%v", a.Key, a.Value))
+		h.appendAttr(&b, h.prefix, a)
 		return true
 	})
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, b.String())
+	return err
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	var b strings.Builder
+	for _, a := range attrs {
+		h.appendAttr(&b, h.prefix, a)
+	}
+	h2 := *h
+	h2.attrs += b.String()
+	return &h2
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
 
-	msg := fmt.Sprintf("%s %s %s", colorGray(timestamp), levelStr, r.Message)
-	if len(attrs) > 0 {
-		msg += " " + strings.Join(attrs, " ")
+// appendAttr writes " key=value" for an attribute, and for each attribute
+// of a group, with keys prefixed by the groups they are in.
+func (h *PrettyHandler) appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(b, prefix, ga)
+		}
+		return
 	}
-	fmt.Fprintln(h.out, msg)
-	return nil
+	b.WriteString(" ")
+	b.WriteString(h.paint(colorGray, prefix+a.Key+"="))
+	b.WriteString(formatValue(a.Value))
 }
 
-func (h *PrettyHandler) WithAttrs(_ []slog.Attr) slog.Handler { return h }
-func (h *PrettyHandler) WithGroup(_ string) slog.Handler      { return h }
+// formatValue formats a value, quoting strings that would otherwise be
+// ambiguous.
+func formatValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339)
+	default:
+		s = v.String()
+	}
+	if s == "" || strings.IndexFunc(s, needsQuote) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuote(r rune) bool {
+	return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+}
+
+func (h *PrettyHandler) paint(color func(string) string, s string) string {
+	if !h.color {
+		return s
+	}
+	return color(s)
+}
+
+// isTerminal reports whether out is a terminal, which colors can be
+// written to.
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
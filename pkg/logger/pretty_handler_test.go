@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+func TestPrettyHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewPrettyHandler(&buf, slog.HandlerOptions{Level: slog.LevelDebug})
+	results := func() []map[string]any {
+		var ms []map[string]any
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			m, err := parseLine(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	if err := slogtest.TestHandler(h, results); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// linePattern splits a line into its time, level and the rest.
+var linePattern = regexp.MustCompile(`^(?:(\d\d:\d\d:\d\d) )?\[(\w+)\] (.*)$`)
+
+// parseLine parses a line written by PrettyHandler into a map, with a
+// nested map for each group of a dotted key.
+func parseLine(line string) (map[string]any, error) {
+	match := linePattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("malformed line %q", line)
+	}
+	m := map[string]any{slog.LevelKey: match[2]}
+	if match[1] != "" {
+		m[slog.TimeKey] = match[1]
+	}
+	var msg []string
+	for rest := match[3]; rest != ""; {
+		rest = strings.TrimLeft(rest, " ")
+		i := strings.IndexAny(rest, " =")
+		if i < 0 || rest[i] == ' ' {
+			// Words before the first attribute are the message.
+			word, after, _ := strings.Cut(rest, " ")
+			msg = append(msg, word)
+			rest = after
+			continue
+		}
+		key, value := rest[:i], rest[i+1:]
+		var val string
+		if strings.HasPrefix(value, `"`) {
+			q, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, err
+			}
+			val, _ = strconv.Unquote(q)
+			rest = value[len(q):]
+		} else {
+			val, rest, _ = strings.Cut(value, " ")
+		}
+		group := m
+		keys := strings.Split(key, ".")
+		for _, k := range keys[:len(keys)-1] {
+			g, ok := group[k].(map[string]any)
+			if !ok {
+				g = map[string]any{}
+				group[k] = g
+			}
+			group = g
+		}
+		group[keys[len(keys)-1]] = val
+	}
+	m[slog.MessageKey] = strings.Join(msg, " ")
+	return m, nil
+}
+
+func TestPrettyHandlerFormat(t *testing.T) {
+	at := time.Date(2025, 1, 20, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		logger func(*slog.Logger) *slog.Logger
+		attrs  []slog.Attr
+		want   string
+	}{
+		{"plain", nil, []slog.Attr{slog.String("feed", "lwn"), slog.Int("new", 3)},
+			"feed=lwn new=3"},
+		{"quoting", nil, []slog.Attr{slog.String("a", "x y"), slog.String("b", ""), slog.String("c", "k=v"),
+			slog.String("d", "tab\there")},
+			`a="x y" b="" c="k=v" d="tab\there"`},
+		{"values", nil, []slog.Attr{slog.Duration("took", 412*time.Millisecond), slog.Time("at", at),
+			slog.Bool("ok", true), slog.Any("err", fmt.Errorf("boom"))},
+			"took=412ms at=2025-01-20T15:04:05Z ok=true err=boom"},
+		{"with attrs", func(l *slog.Logger) *slog.Logger { return l.With("component", "aggregator") },
+			[]slog.Attr{slog.String("feed", "lwn")},
+			"component=aggregator feed=lwn"},
+		{"group attr", nil, []slog.Attr{slog.Group("http", slog.Int("status", 200), slog.String("method", "GET"))},
+			"http.status=200 http.method=GET"},
+		{"inline group", nil, []slog.Attr{slog.Group("", slog.Int("a", 1)), slog.Int("b", 2)},
+			"a=1 b=2"},
+		{"empty group", nil, []slog.Attr{slog.Group("g"), slog.Int("b", 2)},
+			"b=2"},
+		{"with group", func(l *slog.Logger) *slog.Logger { return l.WithGroup("req") },
+			[]slog.Attr{slog.Int("id", 7)},
+			"req.id=7"},
+		{"nested groups", func(l *slog.Logger) *slog.Logger {
+			return l.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h")
+		},
+			[]slog.Attr{slog.Int("c", 3), slog.Group("i", slog.Int("d", 4))},
+			"a=1 g.b=2 g.h.c=3 g.h.i.d=4"},
+		{"empty with group", func(l *slog.Logger) *slog.Logger { return l.WithGroup("") },
+			[]slog.Attr{slog.Int("a", 1)},
+			"a=1"},
+		{"with group without attrs", func(l *slog.Logger) *slog.Logger { return l.With("a", 1).WithGroup("g") },
+			nil,
+			"a=1"},
+		{"log valuer", nil, []slog.Attr{slog.Any("user", userValue{"ann"})},
+			"user.name=ann"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := slog.New(NewPrettyHandler(&buf, slog.HandlerOptions{}))
+			if tt.logger != nil {
+				l = tt.logger(l)
+			}
+			l.LogAttrs(context.Background(), slog.LevelInfo, "Feed fetched", tt.attrs...)
+			got := strings.TrimSuffix(buf.String(), "\n")
+			want := "[INFO] Feed fetched"
+			if tt.want != "" {
+				want += " " + tt.want
+			}
+			if _, rest, ok := strings.Cut(got, " "); !ok || rest != want {
+				t.Errorf("got %q, want %q after the time", got, want)
+			}
+		})
+	}
+}
+
+// userValue logs as a group.
+type userValue struct{ name string }
+
+func (u userValue) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", u.name))
+}
+
+func TestPrettyHandlerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewPrettyHandler(&buf, slog.HandlerOptions{Level: slog.LevelWarn}))
+	l.Info("hidden")
+	l.Warn("shown")
+	l.Log(context.Background(), slog.LevelError+2, "custom")
+	got := regexp.MustCompile(`\d\d:\d\d:\d\d `).ReplaceAllString(buf.String(), "")
+	if want := "[WARN] shown\n[ERROR+2] custom\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
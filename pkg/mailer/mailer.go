@@ -0,0 +1,103 @@
+// Package mailer sends email through an SMTP server.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message is an email with a plain text body and, optionally, an HTML
+// alternative.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends messages through an SMTP server, authenticating if a
+// username is set. The connection is upgraded with STARTTLS if the server
+// supports it.
+type Mailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+// Send sends a message.
+func (m *Mailer) Send(msg Message) error {
+	if m.Addr == "" {
+		return fmt.Errorf("SMTP is not configured")
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+	body, err := msg.encode(m.From, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, msg.To, body)
+}
+
+// encode renders the message as RFC 5322 text, with a multipart/alternative
+// body if it has an HTML part.
+func (msg Message) encode(from string, date time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		writePart(&b, "text/plain", msg.Text)
+		return b.Bytes(), nil
+	}
+
+	boundary, err := newBoundary()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&b, "--%s\r\n", boundary)
+	writePart(&b, "text/plain", msg.Text)
+	fmt.Fprintf(&b, "\r\n--%s\r\n", boundary)
+	writePart(&b, "text/html", msg.HTML)
+	fmt.Fprintf(&b, "\r\n--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// writePart writes the headers and quoted-printable body of a UTF-8 part.
+func writePart(b *bytes.Buffer, contentType, body string) {
+	fmt.Fprintf(b, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(b)
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	w.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	w.Close()
+}
+
+func newBoundary() (string, error) {
+	r := make([]byte, 16)
+	if _, err := rand.Read(r); err != nil {
+		return "", err
+	}
+	return "rsshub-" + hex.EncodeToString(r), nil
+}
@@ -0,0 +1,229 @@
+package readability
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+
+	"rsshub/pkg/dom"
+	"rsshub/pkg/sanitize"
+)
+
+// ErrNoContent is returned when no part of the page looks like an article.
+var ErrNoContent = errors.New("no article content found")
+
+// minTextLength is the shortest extracted text accepted as an article.
+const minTextLength = 250
+
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|header|menu|modal|nav|newsletter|pagination|popup|promo|related|remark|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|tool|widget|-ad-|^ad-|advert`)
+	maybePattern    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|story|text|blog`)
+	negativePattern = regexp.MustCompile(`(?i)comment|com-|contact|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|taboola|widget|hidden`)
+)
+
+// removedTags never contain article text.
+var removedTags = map[string]bool{
+	"aside": true, "button": true, "footer": true, "form": true, "header": true, "iframe": true,
+	"nav": true, "noscript": true, "script": true, "style": true, "svg": true, "template": true,
+}
+
+// Extract finds the main content of an HTML page the way browser reader
+// modes do: paragraphs are scored by their length and punctuation, the
+// scores are propagated to their containers, and the best container is
+// returned together with related siblings, as sanitized HTML with links
+// resolved against pageURL.
+func Extract(page, pageURL string) (string, error) {
+	doc := dom.Parse(page)
+	body := doc.Find("body")
+	if body == nil {
+		body = doc
+	}
+	prune(body)
+
+	scores := make(map[*dom.Node]float64)
+	var candidates []*dom.Node // in document order, so that ties are stable
+	for _, p := range paragraphs(body) {
+		text := strings.TrimSpace(p.Text())
+		if len(text) < 25 {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		parent := p.Parent
+		for level := 0; parent != nil && parent.Type == dom.ElementNode && level < 3; level++ {
+			if _, ok := scores[parent]; !ok {
+				scores[parent] = initialScore(parent)
+				candidates = append(candidates, parent)
+			}
+			switch level {
+			case 0:
+				scores[parent] += score
+			case 1:
+				scores[parent] += score / 2
+			default:
+				scores[parent] += score / 6
+			}
+			parent = parent.Parent
+		}
+	}
+
+	var top *dom.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > scores[top] {
+			top = n
+		}
+	}
+	if top == nil {
+		return "", ErrNoContent
+	}
+
+	content := &dom.Node{Type: dom.ElementNode, Data: "div"}
+	threshold := math.Max(10, scores[top]*0.2)
+	siblings := []*dom.Node{top}
+	if top.Parent != nil {
+		siblings = top.Parent.Children
+	}
+	for _, s := range siblings {
+		if s == top || keepSibling(s, scores, threshold) {
+			content.Children = append(content.Children, s)
+		}
+	}
+
+	html := sanitize.HTML(dom.RenderChildren(content), pageURL)
+	if len(sanitize.Text(html)) < minTextLength {
+		return "", ErrNoContent
+	}
+	return html, nil
+}
+
+// prune removes elements that never hold the article: navigation, forms,
+// scripts and containers whose class or id mark them as comments, sharing
+// buttons, sidebars and the like.
+func prune(n *dom.Node) {
+	kept := n.Children[:0]
+	for _, c := range n.Children {
+		if c.Type == dom.CommentNode {
+			continue
+		}
+		if c.Type == dom.ElementNode {
+			if removedTags[c.Data] {
+				continue
+			}
+			match := c.Attr("class") + " " + c.Attr("id")
+			if c.Data != "body" && c.Data != "article" && c.Data != "main" &&
+				unlikelyPattern.MatchString(match) && !maybePattern.MatchString(match) {
+				continue
+			}
+			prune(c)
+		}
+		kept = append(kept, c)
+	}
+	n.Children = kept
+}
+
+// paragraphs returns the elements scored for their text: paragraphs,
+// preformatted blocks, table cells and divs used as paragraphs.
+func paragraphs(n *dom.Node) []*dom.Node {
+	var found []*dom.Node
+	n.Walk(func(c *dom.Node) bool {
+		if c.Type != dom.ElementNode {
+			return true
+		}
+		switch c.Data {
+		case "p", "pre", "td":
+			found = append(found, c)
+			return false
+		case "div", "section":
+			if !hasBlockChild(c) {
+				found = append(found, c)
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+func hasBlockChild(n *dom.Node) bool {
+	for _, c := range n.Children {
+		if c.Type != dom.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "p", "div", "section", "article", "blockquote", "pre", "table", "ul", "ol", "dl",
+			"h1", "h2", "h3", "h4", "h5", "h6", "figure":
+			return true
+		}
+	}
+	return false
+}
+
+func initialScore(n *dom.Node) float64 {
+	score := classWeight(n)
+	switch n.Data {
+	case "article":
+		score += 10
+	case "div", "section", "main":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+// classWeight rewards class and id names that suggest content and
+// penalizes those that suggest page furniture.
+func classWeight(n *dom.Node) float64 {
+	var weight float64
+	for _, name := range []string{n.Attr("class"), n.Attr("id")} {
+		if name == "" {
+			continue
+		}
+		if negativePattern.MatchString(name) {
+			weight -= 25
+		}
+		if positivePattern.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of the text of n that is inside links.
+func linkDensity(n *dom.Node) float64 {
+	text := len(strings.TrimSpace(n.Text()))
+	if text == 0 {
+		return 0
+	}
+	var links int
+	for _, a := range n.FindAll("a") {
+		links += len(strings.TrimSpace(a.Text()))
+	}
+	return float64(links) / float64(text)
+}
+
+// keepSibling reports whether a sibling of the top candidate belongs to the
+// article too: a well-scored container or a paragraph of running text.
+func keepSibling(n *dom.Node, scores map[*dom.Node]float64, threshold float64) bool {
+	if n.Type != dom.ElementNode {
+		return false
+	}
+	if score, ok := scores[n]; ok && score+classWeight(n) >= threshold {
+		return true
+	}
+	if n.Data != "p" {
+		return false
+	}
+	text := strings.TrimSpace(n.Text())
+	density := linkDensity(n)
+	if len(text) > 80 && density < 0.25 {
+		return true
+	}
+	return len(text) > 0 && density == 0 && strings.ContainsAny(text, ".!?")
+}
@@ -0,0 +1,63 @@
+package readability
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const article = `<!DOCTYPE html>
+<html><head><title>Post</title><script>var x = "<p>not text</p>";</script></head>
+<body>
+<header><nav><a href="/">Home</a> <a href="/about">About</a></nav></header>
+<div class="sidebar"><p>Subscribe to our newsletter, it is really good, we promise, and free.</p></div>
+<div id="main">
+  <article class="post">
+    <h1>How the parser works</h1>
+    <p>The parser reads the document once, building a tree of nodes as it goes, and recovers from
+       markup errors the way browsers do, so that broken pages still produce a useful result.</p>
+    <p>Each paragraph is scored by its length and by the number of commas in it, and the scores are
+       added to its parent and, halved, to its grandparent, which favours containers of prose.</p>
+    <p>Finally the best container is returned with its related siblings, after <a href="/sanitize">sanitizing</a>
+       it, so that only <em>safe</em> markup is left.<script>alert(1)</script></p>
+    <img src="/figure.png" alt="Figure">
+  </article>
+  <div class="comments"><p>Great post, thanks, I learned a lot from it, really, a lot, truly.</p></div>
+</div>
+<footer><p>Copyright, all rights reserved, contact us, privacy policy, terms.</p></footer>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	got, err := Extract(article, "https://blog.example/posts/parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"The parser reads the document once",
+		"which favours containers of prose",
+		`<a href="https://blog.example/sanitize" rel="noopener noreferrer">sanitizing</a>`,
+		"<em>safe</em>",
+		`src="https://blog.example/figure.png"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Extract() lacks %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Home", "newsletter", "Great post", "Copyright", "alert", "not text", "<script"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Extract() contains %q:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	for name, page := range map[string]string{
+		"empty":      "",
+		"short":      "<p>Too short to be an article, even with a comma.</p>",
+		"navigation": `<nav><p>` + strings.Repeat("Home, about, contact, archive. ", 20) + `</p></nav>`,
+	} {
+		if got, err := Extract(page, "https://blog.example/"); !errors.Is(err, ErrNoContent) {
+			t.Errorf("Extract(%s) = %q, %v, want ErrNoContent", name, got, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package sanitize
+
+import (
+	"strings"
+
+	"rsshub/pkg/dom"
+)
+
+// markdownEscaper escapes the characters that would otherwise start
+// Markdown formatting in running text.
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+// Markdown renders an HTML fragment as Markdown for terminal output:
+// headings, emphasis, code, links, images, block quotes, lists and rules
+// are converted and everything else is reduced to its text.
+func Markdown(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	w := &writer{}
+	markdownNodes(w, dom.ParseFragment(s))
+	return w.String()
+}
+
+func markdownNodes(w *writer, n *dom.Node) {
+	for _, c := range n.Children {
+		switch c.Type {
+		case dom.TextNode:
+			if w.verbatim {
+				w.text(c.Data)
+			} else {
+				w.text(markdownEscaper.Replace(c.Data))
+			}
+		case dom.ElementNode:
+			if droppedTags[c.Data] {
+				continue
+			}
+			markdownElement(w, c)
+		}
+	}
+}
+
+func markdownElement(w *writer, n *dom.Node) {
+	switch n.Data {
+	case "br":
+		w.lineBreak(1)
+	case "hr":
+		w.lineBreak(2)
+		w.raw("---")
+		w.lineBreak(2)
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.lineBreak(2)
+		w.raw(strings.Repeat("#", int(n.Data[1]-'0')))
+		w.space = true
+		markdownNodes(w, n)
+		w.lineBreak(2)
+	case "b", "strong":
+		inline(w, n, "**")
+	case "i", "em":
+		inline(w, n, "*")
+	case "s", "del":
+		inline(w, n, "~~")
+	case "code":
+		if w.verbatim {
+			markdownNodes(w, n)
+			return
+		}
+		w.raw("`" + strings.Join(strings.Fields(n.Text()), " ") + "`")
+	case "pre":
+		w.lineBreak(2)
+		w.raw("```")
+		w.lineBreak(1)
+		w.verbatim = true
+		markdownNodes(w, n)
+		w.verbatim = false
+		w.lineBreak(1)
+		w.raw("```")
+		w.lineBreak(2)
+	case "a":
+		href := n.Attr("href")
+		text := Markdown(dom.RenderChildren(n))
+		switch {
+		case href == "":
+			w.raw(text)
+		case text == "":
+			w.raw("<" + href + ">")
+		default:
+			w.raw("[" + text + "](" + href + ")")
+		}
+	case "img":
+		if src := n.Attr("src"); src != "" {
+			w.raw("![" + markdownEscaper.Replace(n.Attr("alt")) + "](" + src + ")")
+		}
+	case "blockquote":
+		w.lineBreak(2)
+		w.flush()
+		prefix := w.prefix
+		w.prefix += "> "
+		w.quote = true
+		markdownNodes(w, n)
+		w.quote = false
+		w.prefix = prefix
+		w.lineBreak(2)
+	case "li":
+		w.lineBreak(1)
+		w.raw(listMarker(n, "- "))
+		markdownNodes(w, n)
+	case "ul", "ol":
+		w.lineBreak(2)
+		markdownNodes(w, n)
+		w.lineBreak(2)
+	case "td", "th":
+		w.text(" ")
+		markdownNodes(w, n)
+	default:
+		if blockTags[n.Data] {
+			w.lineBreak(2)
+			markdownNodes(w, n)
+			w.lineBreak(2)
+			return
+		}
+		markdownNodes(w, n)
+	}
+}
+
+// inline wraps the content of n in the Markdown delimiter, keeping
+// surrounding whitespace outside of it.
+func inline(w *writer, n *dom.Node, delim string) {
+	text := Markdown(dom.RenderChildren(n))
+	if text == "" {
+		return
+	}
+	w.raw(delim + text + delim)
+}
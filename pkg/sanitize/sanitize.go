@@ -0,0 +1,191 @@
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+
+	"rsshub/pkg/dom"
+)
+
+// allowedTags maps the elements kept in sanitized HTML to the attributes
+// they may keep. Elements not listed are unwrapped: their content stays,
+// the element itself is dropped.
+var allowedTags = map[string][]string{
+	"a":          {"href", "title"},
+	"abbr":       {"title"},
+	"b":          nil,
+	"blockquote": {"cite"},
+	"br":         nil,
+	"caption":    nil,
+	"code":       nil,
+	"dd":         nil,
+	"del":        nil,
+	"dl":         nil,
+	"dt":         nil,
+	"em":         nil,
+	"figcaption": nil,
+	"figure":     nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"hr":         nil,
+	"i":          nil,
+	"img":        {"src", "alt", "title", "width", "height"},
+	"ins":        nil,
+	"li":         nil,
+	"ol":         {"start"},
+	"p":          nil,
+	"pre":        nil,
+	"q":          {"cite"},
+	"s":          nil,
+	"small":      nil,
+	"strong":     nil,
+	"sub":        nil,
+	"sup":        nil,
+	"table":      nil,
+	"tbody":      nil,
+	"td":         {"colspan", "rowspan"},
+	"tfoot":      nil,
+	"th":         {"colspan", "rowspan"},
+	"thead":      nil,
+	"tr":         nil,
+	"u":          nil,
+	"ul":         nil,
+}
+
+// droppedTags are removed together with their content.
+var droppedTags = map[string]bool{
+	"applet":   true,
+	"audio":    true,
+	"button":   true,
+	"embed":    true,
+	"form":     true,
+	"frame":    true,
+	"frameset": true,
+	"head":     true,
+	"iframe":   true,
+	"input":    true,
+	"link":     true,
+	"math":     true,
+	"meta":     true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"select":   true,
+	"style":    true,
+	"svg":      true,
+	"template": true,
+	"textarea": true,
+	"title":    true,
+	"video":    true,
+}
+
+// urlAttrs are the attributes holding links, which are resolved and
+// restricted to safe schemes.
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+// trackerHosts serve tracking pixels and nothing else worth showing.
+var trackerHosts = []string{
+	"pixel.wp.com",
+	"stats.wordpress.com",
+	"feeds.feedburner.com/~r/",
+	"feeds.feedburner.com/~ff/",
+	"feedads.g.doubleclick.net",
+	"pixel.quantserve.com",
+}
+
+// HTML returns s with everything but an allow-list of formatting elements
+// and attributes removed. Scripts, styles, frames, forms and embedded
+// objects are dropped with their content, tracking pixels are removed,
+// links are resolved against base and limited to http, https and mailto,
+// and every link gets rel="noopener noreferrer".
+func HTML(s, base string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	baseURL, _ := url.Parse(base)
+	doc := dom.ParseFragment(s)
+	out := &dom.Node{Type: dom.DocumentNode}
+	clean(out, doc, baseURL)
+	return strings.TrimSpace(dom.Render(out))
+}
+
+// clean appends the sanitized children of n to out.
+func clean(out, n *dom.Node, base *url.URL) {
+	for _, c := range n.Children {
+		switch c.Type {
+		case dom.TextNode:
+			out.AppendChild(&dom.Node{Type: dom.TextNode, Data: c.Data})
+		case dom.ElementNode:
+			if droppedTags[c.Data] {
+				continue
+			}
+			attrs, ok := allowedTags[c.Data]
+			if !ok {
+				clean(out, c, base)
+				continue
+			}
+			el := &dom.Node{Type: dom.ElementNode, Data: c.Data}
+			for _, key := range attrs {
+				if !c.HasAttr(key) {
+					continue
+				}
+				val := c.Attr(key)
+				if urlAttrs[key] {
+					val = safeURL(val, base)
+					if val == "" {
+						continue
+					}
+				}
+				el.Attrs = append(el.Attrs, dom.Attr{Key: key, Val: val})
+			}
+			switch c.Data {
+			case "img":
+				if el.Attr("src") == "" || isTrackingPixel(el) {
+					continue
+				}
+			case "a":
+				if el.HasAttr("href") {
+					el.Attrs = append(el.Attrs, dom.Attr{Key: "rel", Val: "noopener noreferrer"})
+				}
+			}
+			out.AppendChild(el)
+			clean(el, c, base)
+		}
+	}
+}
+
+// safeURL resolves raw against base and returns it if it uses a safe
+// scheme, or "" otherwise.
+func safeURL(raw string, base *url.URL) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "":
+		return u.String()
+	}
+	return ""
+}
+
+func isTrackingPixel(img *dom.Node) bool {
+	if img.Attr("width") == "1" && img.Attr("height") == "1" ||
+		img.Attr("width") == "0" || img.Attr("height") == "0" {
+		return true
+	}
+	src := img.Attr("src")
+	for _, host := range trackerHosts {
+		if strings.Contains(src, "://"+host) {
+			return true
+		}
+	}
+	return false
+}
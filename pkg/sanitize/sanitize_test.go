@@ -0,0 +1,132 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+const testBase = "https://example.com/posts/1"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// Scripting URLs, however they are spelled.
+		{"javascript", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript mixed case", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript leading space", `<a href="  javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript entity", `<a href="&#106;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript hex entity", `<a href="&#x6A;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript tab entity", `<a href="jav&#x09;ascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript newline", "<a href=\"java\nscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"javascript named entity", `<a href="javascript&colon;alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript unquoted", `<a href=javascript:alert(1)>x</a>`, `<a>x</a>`},
+		{"vbscript", `<a href="vbscript:msgbox(1)">x</a>`, `<a>x</a>`},
+		{"data URL", `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`, `<a>x</a>`},
+		{"data image", `<img src="data:image/svg+xml,&lt;svg onload=alert(1)&gt;">`, ``},
+		{"javascript img", `<img src="javascript:alert(1)">`, ``},
+		{"javascript cite", `<blockquote cite="javascript:alert(1)">q</blockquote>`, `<blockquote>q</blockquote>`},
+
+		// Links that are kept.
+		{"relative", `<a href="../2">next</a>`, `<a href="https://example.com/2" rel="noopener noreferrer">next</a>`},
+		{"protocol-relative", `<a href="//cdn.example.net/a.png">a</a>`, `<a href="https://cdn.example.net/a.png" rel="noopener noreferrer">a</a>`},
+		{"mailto", `<a href="mailto:jane@example.com">mail</a>`, `<a href="mailto:jane@example.com" rel="noopener noreferrer">mail</a>`},
+		{"own rel and target dropped", `<a href="/x" rel="opener" target="_blank">x</a>`, `<a href="https://example.com/x" rel="noopener noreferrer">x</a>`},
+
+		// Event handlers and other attributes.
+		{"onerror", `<img src="a.png" onerror="alert(1)">`, `<img src="https://example.com/posts/a.png">`},
+		{"onclick", `<p onclick="alert(1)">hi</p>`, `<p>hi</p>`},
+		{"onmouseover unquoted", `<b onmouseover=alert(1)>hi</b>`, `<b>hi</b>`},
+		{"style", `<p style="background:url(javascript:alert(1))">hi</p>`, `<p>hi</p>`},
+		{"handler on unwrapped element", `<div onload="alert(1)">hi</div>`, `hi`},
+		{"attribute quote breakout", `<img src="a.png" alt="x&quot; onerror=&quot;alert(1)">`, `<img src="https://example.com/posts/a.png" alt="x&#34; onerror=&#34;alert(1)">`},
+
+		// Elements dropped with their content.
+		{"script", `a<script>alert(1)</script>b`, `ab`},
+		{"script uppercase", `a<SCRIPT>alert(1)</SCRIPT>b`, `ab`},
+		{"script unterminated", `a<script>alert(1)`, `a`},
+		{"script end tag in string", `a<script>"</scr" + "ipt>"</script>b`, `ab`},
+		{"split script", `<scr<script>ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{"style", `<style>body{background:url(javascript:alert(1))}</style>x`, `x`},
+		{"svg", `<svg onload="alert(1)"><script>alert(1)</script><a href="javascript:alert(1)">x</a></svg>y`, `y`},
+		{"math", `<math><mtext><a href="javascript:alert(1)">x</a></mtext></math>y`, `y`},
+		{"iframe", `<iframe src="https://evil.example/"></iframe>x`, `x`},
+		{"object", `<object data="evil.swf"><embed src="evil.swf"></object>x`, `x`},
+		{"form", `<form action="https://evil.example/"><input name="p"></form>x`, `x`},
+		// The open <p> makes the parser ignore </noscript>, so y stays in it.
+		{"noscript breakout", `<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>y`, ``},
+		{"textarea breakout", `<textarea></textarea><script>alert(1)</script></textarea>y`, `y`},
+		{"template", `<template><img src=x onerror=alert(1)></template>y`, `y`},
+
+		// Comments and other markup.
+		{"comment", `a<!-- <script>alert(1)</script> -->b`, `ab`},
+		{"conditional comment", `a<!--[if IE]><script>alert(1)</script><![endif]-->b`, `ab`},
+		{"unterminated comment", `a<!-- <img src=x onerror=alert(1)>`, `a`},
+		{"doctype", `<!DOCTYPE html><p>hi</p>`, `<p>hi</p>`},
+		{"stray brackets", `1 < 2 > 0 & done`, `1 &lt; 2 &gt; 0 &amp; done`},
+
+		// Tracking pixels.
+		{"one pixel", `<img src="/p.gif" width="1" height="1">`, ``},
+		{"tracker host", `<img src="https://pixel.wp.com/g.gif?x=1">`, ``},
+		{"image", `<img src="/a.png" width="640" alt="A">`, `<img src="https://example.com/a.png" alt="A" width="640">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.in, testBase); got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHTMLNoScript checks that no vector leaves markup able to run script,
+// whatever the exact output.
+func TestHTMLNoScript(t *testing.T) {
+	vectors := []string{
+		`<<script>script>alert(1)<</script>/script>`,
+		`<img src=x onerror=alert(1)//`,
+		`<img/src="x"/onerror="alert(1)">`,
+		`<a href="java&#0000115;cript:alert(1)">x</a>`,
+		`<a href="java&Tab;script:alert(1)">x</a>`,
+		`<a href="&#x20;javascript:alert(1)">x</a>`,
+		`<a href="\x01javascript:alert(1)">x</a>`,
+		`<svg><a xlink:href="javascript:alert(1)"><text>x</text></a></svg>`,
+		`<math href="javascript:alert(1)">x</math>`,
+		`<iframe srcdoc="&lt;script&gt;alert(1)&lt;/script&gt;"></iframe>`,
+		`<body onload=alert(1)>`,
+		`<p>a</p><!--><script>alert(1)</script>-->`,
+		`<!--<!--<script>alert(1)</script>-->-->`,
+		`<scr<script>ipt>alert(1)</scr</script>ipt>`,
+		`<a href="https://example.com/" onclick="alert(1)" onClick="alert(2)">x</a>`,
+		`<xmp><script>alert(1)</script></xmp>`,
+		`<title><script>alert(1)</script></title>`,
+		`<noscript><script>alert(1)</script></noscript>`,
+	}
+	for _, in := range vectors {
+		got := strings.ToLower(HTML(in, testBase))
+		for _, bad := range []string{"<script", "javascript:", " on", "<svg", "<math", "<iframe"} {
+			if strings.Contains(got, bad) {
+				t.Errorf("HTML(%q) = %q, contains %q", in, got, bad)
+			}
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`<p>Hello <b>world</b></p><p>Second&nbsp;paragraph</p>`, "Hello world\n\nSecond paragraph"},
+		{`a<script>alert(1)</script><style>p{}</style>b`, "ab"},
+		{`<!-- hidden -->shown`, "shown"},
+		{`1 &lt; 2 &amp;&amp; 3 &gt; 2`, "1 < 2 && 3 > 2"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package sanitize
+
+import (
+	"strconv"
+	"strings"
+
+	"rsshub/pkg/dom"
+)
+
+// blockTags start on a new paragraph in text and Markdown output.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "tr": true, "ul": true,
+}
+
+// writer collects text and collapses whitespace, emitting at most the
+// requested number of line breaks between blocks.
+type writer struct {
+	b        strings.Builder
+	prefix   string // written at the start of each line, e.g. "> "
+	breaks   int    // line breaks owed before the next text
+	space    bool   // a space is owed before the next text
+	verbatim bool   // inside <pre>
+	quote    bool   // a new quote starts with the next text
+}
+
+func (w *writer) text(s string) {
+	if w.verbatim {
+		w.flush()
+		s = strings.ReplaceAll(s, "\n", "\n"+w.prefix)
+		w.b.WriteString(s)
+		return
+	}
+	if s != "" && isSpaceRune(rune(s[0])) {
+		w.space = true
+	}
+	for _, field := range strings.FieldsFunc(s, isSpaceRune) {
+		if w.b.Len() > 0 && w.breaks == 0 && w.space {
+			w.b.WriteByte(' ')
+		}
+		w.flush()
+		w.b.WriteString(field)
+		w.space = true
+	}
+	if len(s) > 0 && isSpaceRune(rune(s[len(s)-1])) {
+		w.space = true
+	} else if len(s) > 0 {
+		w.space = false
+	}
+}
+
+// raw writes s as is, after any pending breaks.
+func (w *writer) raw(s string) {
+	if w.b.Len() > 0 && w.breaks == 0 && w.space {
+		w.b.WriteByte(' ')
+	}
+	w.flush()
+	w.b.WriteString(s)
+	w.space = false
+}
+
+func (w *writer) flush() {
+	if w.quote {
+		w.b.WriteString(w.prefix)
+		w.quote = false
+		w.breaks = 0
+		return
+	}
+	if w.b.Len() == 0 {
+		w.breaks = 0
+		return
+	}
+	// Blank lines inside a quote carry the marker without trailing space.
+	for ; w.breaks > 1; w.breaks-- {
+		w.b.WriteString("\n" + strings.TrimRight(w.prefix, " "))
+	}
+	if w.breaks == 1 {
+		w.b.WriteString("\n" + w.prefix)
+		w.breaks = 0
+	}
+}
+
+func (w *writer) lineBreak(n int) {
+	w.breaks = max(w.breaks, n)
+	w.space = false
+}
+
+func (w *writer) String() string {
+	return strings.TrimSpace(w.b.String())
+}
+
+func isSpaceRune(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == ' '
+}
+
+// Text returns the readable text of an HTML fragment: paragraphs and other
+// blocks are separated by blank lines, <br> becomes a line break, list items
+// are prefixed with "- " (or their number) and whitespace is collapsed.
+func Text(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	w := &writer{}
+	textNodes(w, dom.ParseFragment(s))
+	return w.String()
+}
+
+func textNodes(w *writer, n *dom.Node) {
+	for _, c := range n.Children {
+		switch c.Type {
+		case dom.TextNode:
+			w.text(c.Data)
+		case dom.ElementNode:
+			if droppedTags[c.Data] {
+				continue
+			}
+			switch c.Data {
+			case "br":
+				w.lineBreak(1)
+				continue
+			case "li":
+				w.lineBreak(1)
+				w.raw(listMarker(c, "- "))
+				textNodes(w, c)
+				continue
+			case "td", "th":
+				w.text(" ")
+				textNodes(w, c)
+				continue
+			case "img":
+				if alt := strings.TrimSpace(c.Attr("alt")); alt != "" {
+					w.text(" " + alt + " ")
+				}
+				continue
+			case "pre":
+				w.lineBreak(2)
+				w.verbatim = true
+				textNodes(w, c)
+				w.verbatim = false
+				w.lineBreak(2)
+				continue
+			}
+			if blockTags[c.Data] {
+				w.lineBreak(2)
+				textNodes(w, c)
+				w.lineBreak(2)
+				continue
+			}
+			textNodes(w, c)
+		}
+	}
+}
+
+// listMarker returns "N. " for items of ordered lists and bullet
+// otherwise.
+func listMarker(li *dom.Node, bullet string) string {
+	list := li.Parent
+	if list == nil || list.Data != "ol" {
+		return bullet
+	}
+	n := 1
+	if start, err := strconv.Atoi(list.Attr("start")); err == nil {
+		n = start
+	}
+	for _, sibling := range list.Children {
+		if sibling == li {
+			break
+		}
+		if sibling.Type == dom.ElementNode && sibling.Data == "li" {
+			n++
+		}
+	}
+	return strconv.Itoa(n) + ". "
+}
@@ -0,0 +1,57 @@
+package simhash
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// minFeatures is the number of shingles below which a text is considered
+// too short to fingerprint; short texts collide too easily.
+const minFeatures = 4
+
+// Bands is the number of 16-bit bands into which fingerprints are split to
+// be looked up: fingerprints that differ in fewer than Bands bits agree on
+// at least one of them.
+const Bands = 4
+
+// Compute returns the 64-bit SimHash of text built from its lowercased word
+// unigrams and bigrams. It returns 0 if text is too short to fingerprint.
+func Compute(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var features []string
+	for i, w := range words {
+		features = append(features, w)
+		if i > 0 {
+			features = append(features, words[i-1]+" "+w)
+		}
+	}
+	if len(features) < minFeatures {
+		return 0
+	}
+
+	var weights [64]int
+	for _, f := range features {
+		h := fnv.New64a()
+		h.Write([]byte(f))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
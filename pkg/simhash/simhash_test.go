@@ -0,0 +1,66 @@
+package simhash
+
+import (
+	"math/bits"
+	"testing"
+)
+
+const story = "Apple reports record quarterly revenue of 124 billion dollars, driven by strong iPhone sales " +
+	"in China and growth in services, the company said on Thursday after markets closed."
+
+func TestComputeDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		maxDist int // inclusive
+		minDist int
+	}{
+		{"identical", story, story, 0, 0},
+		{"case and punctuation", story, "APPLE reports record quarterly revenue of 124 billion dollars - driven by strong iPhone sales " +
+			"in China and growth in services; the company said on Thursday after markets closed", 0, 0},
+		{"one word changed", story, "Apple reports record quarterly revenue of 124 billion dollars, driven by strong iPhone sales " +
+			"in China and growth in services, the company said on Friday after markets closed.", 3, 0},
+		{"words added", story, "BREAKING: " + story + " Shares rose.", 3, 0},
+		{"other story", story, "The city council approved a new budget for public transport on Monday, adding night buses " +
+			"and extending tram lines to the northern suburbs by the end of next year.", 64, 10},
+	}
+	for _, tt := range tests {
+		d := bits.OnesCount64(Compute(tt.a) ^ Compute(tt.b))
+		if d > tt.maxDist || d < tt.minDist {
+			t.Errorf("%s: distance %d, want %d to %d", tt.name, d, tt.minDist, tt.maxDist)
+		}
+	}
+}
+
+func TestComputeShort(t *testing.T) {
+	for _, text := range []string{"", "   ", "Hello", "Hi there", "!!! ???"} {
+		if h := Compute(text); h != 0 {
+			t.Errorf("Compute(%q) = %x, want 0", text, h)
+		}
+	}
+	if Compute("Go 1.24 is released") == 0 {
+		t.Error("text of four words was not fingerprinted")
+	}
+}
+
+// TestBands checks the property the story lookup relies on: fingerprints
+// that differ in fewer than Bands bits share a 16-bit band.
+func TestBands(t *testing.T) {
+	h := Compute(story)
+	for i := 0; i < 64; i++ {
+		for j := i; j < 64; j++ {
+			for k := j; k < 64; k++ {
+				other := h ^ 1<<i ^ 1<<j ^ 1<<k
+				shared := false
+				for band := 0; band < Bands; band++ {
+					if uint16(h>>(16*band)) == uint16(other>>(16*band)) {
+						shared = true
+					}
+				}
+				if !shared {
+					t.Fatalf("%x and %x share no band", h, other)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewWriterExporter returns an exporter that writes spans to w, e.g.
+// os.Stdout, as JSON objects, one per line.
+func NewWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// fileExporter writes spans to a file that it closes on shutdown.
+type fileExporter struct {
+	sdktrace.SpanExporter
+	f *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.f.Close())
+}
+
+// NewFileExporter returns an exporter that appends spans to the file at
+// path, like NewWriterExporter.
+func NewFileExporter(path string) (sdktrace.SpanExporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	e, err := NewWriterExporter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileExporter{SpanExporter: e, f: f}, nil
+}
+
+// NewOTLPExporter returns an exporter that sends spans to an OpenTelemetry
+// collector with OTLP over HTTP, posting to the traces path of endpoint,
+// e.g. http://localhost:4318, with headers added to requests.
+func NewOTLPExporter(endpoint string, headers map[string]string) (sdktrace.SpanExporter, error) {
+	return otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithHeaders(headers),
+		otlptracehttp.WithTimeout(exportTimeout))
+}
+
+// ParseHeaders parses headers given as comma-separated key=value pairs, as
+// in OTEL_EXPORTER_OTLP_HEADERS.
+func ParseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if k = strings.TrimSpace(k); ok && k != "" {
+			headers[k] = strings.TrimSpace(v)
+		}
+	}
+	return headers
+}
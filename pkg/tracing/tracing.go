@@ -0,0 +1,176 @@
+// Package tracing traces operations with the OpenTelemetry SDK, taking
+// attributes as alternating keys and values like log/slog. Spans are carried
+// in contexts; when no tracer is installed Start returns a nil span, whose
+// methods do nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// queueSize is how many ended spans can wait for export. Spans ended
+	// while the queue is full are dropped.
+	queueSize = 4096
+	// batchSize is the largest number of spans exported at once.
+	batchSize = 256
+	// flushInterval is how often queued spans are exported.
+	flushInterval = 5 * time.Second
+	// exportTimeout bounds one export.
+	exportTimeout = 10 * time.Second
+)
+
+// Span is an operation being traced. A nil span is valid and does nothing.
+type Span struct {
+	span trace.Span
+}
+
+// Set adds attributes given as alternating keys and values.
+func (s *Span) Set(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(attributes(kv)...)
+}
+
+// RecordError marks the span as failed with err. It does nothing if err is
+// nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End ends the span and queues it for export. Only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+// attributes converts alternating keys and values to attributes. Durations
+// are recorded in milliseconds.
+func attributes(kv []any) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		if i+1 >= len(kv) {
+			attrs = append(attrs, attribute.String(key, "!MISSING"))
+			continue
+		}
+		switch v := kv[i+1].(type) {
+		case string:
+			attrs = append(attrs, attribute.String(key, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(key, v))
+		case int:
+			attrs = append(attrs, attribute.Int(key, v))
+		case int64:
+			attrs = append(attrs, attribute.Int64(key, v))
+		case int32:
+			attrs = append(attrs, attribute.Int64(key, int64(v)))
+		case uint32:
+			attrs = append(attrs, attribute.Int64(key, int64(v)))
+		case float64:
+			attrs = append(attrs, attribute.Float64(key, v))
+		case float32:
+			attrs = append(attrs, attribute.Float64(key, float64(v)))
+		case time.Duration:
+			attrs = append(attrs, attribute.Float64(key, float64(v.Microseconds())/1000))
+		case error:
+			attrs = append(attrs, attribute.String(key, v.Error()))
+		case fmt.Stringer:
+			attrs = append(attrs, attribute.String(key, v.String()))
+		default:
+			attrs = append(attrs, attribute.String(key, fmt.Sprint(v)))
+		}
+	}
+	return attrs
+}
+
+// Start starts a span named name as a child of the current span of ctx,
+// or as the root of a new trace, with attributes given as alternating keys
+// and values. It returns a context in which the span is current. End the
+// span when the operation is done.
+func Start(ctx context.Context, name string, kv ...any) (context.Context, *Span) {
+	return start(ctx, name, kv, trace.WithSpanKind(trace.SpanKindInternal))
+}
+
+// StartClient starts a span for a call to another service, such as an HTTP
+// request or a database query.
+func StartClient(ctx context.Context, name string, kv ...any) (context.Context, *Span) {
+	return start(ctx, name, kv, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// StartLinked starts a span named name as the root of a new trace, linked
+// to from, the span of the operation that caused it, such as the batch that
+// the traced operation is part of. from may be nil.
+func StartLinked(ctx context.Context, name string, from *Span, kv ...any) (context.Context, *Span) {
+	opts := []trace.SpanStartOption{trace.WithNewRoot(), trace.WithSpanKind(trace.SpanKindInternal)}
+	if from != nil {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: from.span.SpanContext()}))
+	}
+	return start(ctx, name, kv, opts...)
+}
+
+func start(ctx context.Context, name string, kv []any, opts ...trace.SpanStartOption) (context.Context, *Span) {
+	t := current.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	ctx, span := t.tracer.Start(ctx, name, append(opts, trace.WithAttributes(attributes(kv)...))...)
+	return ctx, &Span{span: span}
+}
+
+// Enabled reports whether a tracer is installed, for callers that want to
+// skip preparing attributes otherwise.
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// current is the installed tracer.
+var current atomic.Pointer[Tracer]
+
+// Tracer exports the spans ended while it is installed, in batches.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// Install starts a tracer that exports spans of service through exporter
+// and makes Start use it.
+func Install(service string, exporter sdktrace.SpanExporter) *Tracer {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxQueueSize(queueSize),
+			sdktrace.WithMaxExportBatchSize(batchSize),
+			sdktrace.WithBatchTimeout(flushInterval),
+			sdktrace.WithExportTimeout(exportTimeout)),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	t := &Tracer{provider: provider, tracer: provider.Tracer("rsshub")}
+	current.Store(t)
+	return t
+}
+
+// Shutdown uninstalls the tracer, exports the spans still queued and
+// shuts the exporter down.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	current.CompareAndSwap(t, nil)
+	return t.provider.Shutdown(ctx)
+}
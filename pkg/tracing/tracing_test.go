@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func install(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := Install("test", exporter)
+	t.Cleanup(func() { tracer.Shutdown(context.Background()) })
+	return exporter
+}
+
+// spans flushes the tracer and returns the exported spans by name.
+func spans(t *testing.T, exporter *tracetest.InMemoryExporter) map[string]tracetest.SpanStub {
+	t.Helper()
+	if err := current.Load().provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]tracetest.SpanStub)
+	for _, s := range exporter.GetSpans() {
+		byName[s.Name] = s
+	}
+	return byName
+}
+
+func TestNoTracer(t *testing.T) {
+	ctx, span := Start(context.Background(), "op", "key", "value")
+	if span != nil || ctx != context.Background() {
+		t.Fatal("span started without a tracer")
+	}
+	span.Set("key", 1)
+	span.RecordError(errors.New("failed"))
+	span.End()
+	if Enabled() {
+		t.Error("Enabled without a tracer")
+	}
+}
+
+func TestSpanTree(t *testing.T) {
+	exporter := install(t)
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := StartClient(ctx, "child")
+	child.End()
+	_, linked := StartLinked(ctx, "linked", parent)
+	linked.End()
+	parent.End()
+
+	got := spans(t, exporter)
+	p, c, l := got["parent"], got["child"], got["linked"]
+	if c.Parent.SpanID() != p.SpanContext.SpanID() || c.SpanContext.TraceID() != p.SpanContext.TraceID() {
+		t.Error("child is not a child of parent")
+	}
+	if c.SpanKind != trace.SpanKindClient || p.SpanKind != trace.SpanKindInternal {
+		t.Errorf("kinds = %v, %v", p.SpanKind, c.SpanKind)
+	}
+	if l.Parent.IsValid() || l.SpanContext.TraceID() == p.SpanContext.TraceID() {
+		t.Error("linked span is not the root of a new trace")
+	}
+	if len(l.Links) != 1 || l.Links[0].SpanContext.SpanID() != p.SpanContext.SpanID() {
+		t.Errorf("links = %+v, want parent", l.Links)
+	}
+	if v, _ := p.Resource.Set().Value("service.name"); v.AsString() != "test" {
+		t.Errorf("service.name = %q", v.AsString())
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	exporter := install(t)
+	_, span := Start(context.Background(), "op", "s", "x", "b", true, "i", 3, "i64", int64(4), "f", 1.5,
+		"d", 1500*time.Microsecond, "err", errors.New("boom"), "odd")
+	span.Set("later", uint32(7))
+	span.RecordError(errors.New("failed"))
+	span.End()
+
+	s := spans(t, exporter)["op"]
+	want := map[attribute.Key]attribute.Value{
+		"s":     attribute.StringValue("x"),
+		"b":     attribute.BoolValue(true),
+		"i":     attribute.IntValue(3),
+		"i64":   attribute.Int64Value(4),
+		"f":     attribute.Float64Value(1.5),
+		"d":     attribute.Float64Value(1.5),
+		"err":   attribute.StringValue("boom"),
+		"odd":   attribute.StringValue("!MISSING"),
+		"later": attribute.Int64Value(7),
+	}
+	got := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k].Emit(), v.Emit())
+		}
+	}
+	if s.Status.Code != codes.Error || s.Status.Description != "failed" {
+		t.Errorf("status = %+v", s.Status)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := Install("test", exporter)
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child", "items", 2)
+	child.End()
+	parent.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var span struct{ Name string }
+		if err := json.Unmarshal(scanner.Bytes(), &span); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		names = append(names, span.Name)
+	}
+	if len(names) != 2 || names[0] != "child" || names[1] != "parent" {
+		t.Errorf("spans = %q, want child and parent, one per line", names)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got := ParseHeaders(" Authorization = Bearer abc ,x-tenant=1,,broken, =empty")
+	if len(got) != 2 || got["Authorization"] != "Bearer abc" || got["x-tenant"] != "1" {
+		t.Errorf("ParseHeaders = %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that only identify the campaign or
+// referrer and never change the page being linked to.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"yclid":   true,
+	"msclkid": true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"ref_src": true,
+}
+
+// IsTrackingParam reports whether the query parameter key is a known
+// tracking parameter (utm_* and friends).
+func IsTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
+
+// Normalize returns the canonical form of raw. Relative links are resolved
+// against base, dot segments are removed from the path, the scheme and host
+// are lowercased, default ports are dropped and tracking parameters are
+// removed; the rest of the query is kept as it was. Fragments are dropped
+// unless they are hash routes, starting with "!" or "/", which name a page
+// of their own. If raw cannot be parsed it is returned trimmed but
+// otherwise unchanged.
+func Normalize(raw, base string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if !u.IsAbs() && base != "" {
+		if b, err := url.Parse(strings.TrimSpace(base)); err == nil {
+			u = b.ResolveReference(u)
+		}
+	} else if u.IsAbs() && u.Opaque == "" {
+		// Resolving an absolute URL against itself only cleans its path.
+		u = u.ResolveReference(u)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+		if strings.Contains(u.Host, ":") {
+			u.Host = "[" + u.Host + "]"
+		}
+	}
+	if u.Host != "" && u.Path == "" {
+		u.Path = "/"
+	}
+	if !strings.HasPrefix(u.Fragment, "!") && !strings.HasPrefix(u.Fragment, "/") {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	u.RawQuery = StripParams(u.RawQuery, IsTrackingParam)
+	return u.String()
+}
+
+// StripParams removes the parameters whose unescaped names drop reports
+// from a raw query, leaving the other parameters in their order and
+// encoding.
+func StripParams(rawQuery string, drop func(key string) bool) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	kept := params[:0]
+	for _, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if !drop(key) {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
@@ -0,0 +1,79 @@
+package urlnorm
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw, base, want string
+	}{
+		{"", "", ""},
+		{"  https://example.com/a  ", "", "https://example.com/a"},
+		{"HTTPS://Example.COM", "", "https://example.com/"},
+		{"http://example.com:80/a", "", "http://example.com/a"},
+		{"https://example.com:443/a", "", "https://example.com/a"},
+		{"https://example.com:8443/a", "", "https://example.com:8443/a"},
+		{"http://[::1]:80/a", "", "http://[::1]/a"},
+
+		// Dot segments are removed from relative and absolute URLs alike.
+		{"../b", "https://example.com/a/c", "https://example.com/b"},
+		{"https://example.com/a/../b", "", "https://example.com/b"},
+		{"https://example.com/a/./b/", "", "https://example.com/a/b/"},
+		{"/post/1", "https://example.com/blog/", "https://example.com/post/1"},
+
+		// Tracking parameters go; the rest of the query is left as is.
+		{"https://example.com/a?utm_source=rss&id=1", "", "https://example.com/a?id=1"},
+		{"https://example.com/a?UTM_Medium=x&fbclid=y", "", "https://example.com/a"},
+		{"https://example.com/a?a=1&b", "", "https://example.com/a?a=1&b"},
+		{"https://example.com/a?z=1&a=2", "", "https://example.com/a?z=1&a=2"},
+		{"https://example.com/a?q=a+b%2Fc&gclid=1", "", "https://example.com/a?q=a+b%2Fc"},
+		{"https://example.com/a?utm%5Fsource=x&b=1", "", "https://example.com/a?b=1"},
+
+		// Fragments are dropped, except hash routes.
+		{"https://example.com/a#comments", "", "https://example.com/a"},
+		{"https://example.com/#!/post/1", "", "https://example.com/#!/post/1"},
+		{"https://example.com/#/post/2", "", "https://example.com/#/post/2"},
+
+		{"mailto:a@example.com", "https://example.com/", "mailto:a@example.com"},
+		{"%zz", "", "%zz"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.raw, tt.base); got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", tt.raw, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestIsTrackingParam(t *testing.T) {
+	for key, want := range map[string]bool{
+		"utm_campaign": true,
+		"UTM_SOURCE":   true,
+		"fbclid":       true,
+		"ref_src":      true,
+		"id":           false,
+		"utm":          false,
+		"page":         false,
+	} {
+		if got := IsTrackingParam(key); got != want {
+			t.Errorf("IsTrackingParam(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestStripParams(t *testing.T) {
+	drop := func(key string) bool { return key == "drop" || key == "a b" }
+	tests := []struct {
+		query, want string
+	}{
+		{"", ""},
+		{"drop=1", ""},
+		{"z=1&drop=2&a=3", "z=1&a=3"},
+		{"drop&keep&drop=", "keep"},
+		{"q=a+b%2Fc&drop=1", "q=a+b%2Fc"},
+		{"d%72op=1&a+b=2&a%20b=3&ok=%zz", "ok=%zz"},
+	}
+	for _, tt := range tests {
+		if got := StripParams(tt.query, drop); got != tt.want {
+			t.Errorf("StripParams(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}